@@ -0,0 +1,275 @@
+// Пакет client - клиент JSON API groupie-tracker, написанный по описанию /openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Ошибка, возвращенная сервером
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("groupie-tracker: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("groupie-tracker: %d %s", e.StatusCode, e.Message)
+}
+
+// Параметры списка групп, пустые значения не передаются
+type ListOptions struct {
+	Sort    string
+	Order   string
+	Page    int
+	PerPage int
+	Cursor  string
+}
+
+func (o ListOptions) values() url.Values {
+	q := url.Values{}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Order != "" {
+		q.Set("order", o.Order)
+	}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	return q
+}
+
+// Клиент API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	user       string
+	password   string
+}
+
+// Функция создания клиента, baseURL - адрес сервера, например http://localhost:8080.
+// Если httpClient равен nil, используется http.DefaultClient.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Функция задания логина и пароля администратора для подписок и управления кэшем
+func (c *Client) SetBasicAuth(user, password string) {
+	c.user = user
+	c.password = password
+}
+
+// Функция отправки запроса, тело ответа закрывает вызывающий
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// Функция выполнения запроса и разбора ответа в out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return readAPIError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Функция разбора ответа сервера с ошибкой {"error": "..."}
+func readAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&e) == nil {
+		apiErr.Message = e.Error
+	}
+	return apiErr
+}
+
+// Функция получения страницы списка групп
+func (c *Client) Bands(ctx context.Context, opts ListOptions) (BandPage, error) {
+	var page BandPage
+	err := c.do(ctx, http.MethodGet, "/api/bands", opts.values(), nil, &page)
+	return page, err
+}
+
+// Функция поиска групп
+func (c *Client) Search(ctx context.Context, query string, opts ListOptions) (BandPage, error) {
+	q := opts.values()
+	q.Set("query", query)
+
+	var page BandPage
+	err := c.do(ctx, http.MethodGet, "/api/search", q, nil, &page)
+	return page, err
+}
+
+// Функция получения подсказок для поиска
+func (c *Client) Suggestions(ctx context.Context) (Search, error) {
+	var search Search
+	err := c.do(ctx, http.MethodGet, "/api/suggestions", nil, nil, &search)
+	return search, err
+}
+
+// Функция получения группы со статистикой тура
+func (c *Client) Band(ctx context.Context, id int) (Band, error) {
+	var band Band
+	err := c.do(ctx, http.MethodGet, "/api/band", url.Values{"id": {strconv.Itoa(id)}}, nil, &band)
+	return band, err
+}
+
+// Функция получения списка снимков данных
+func (c *Client) History(ctx context.Context) ([]SnapshotInfo, error) {
+	var list []SnapshotInfo
+	err := c.do(ctx, http.MethodGet, "/api/history", nil, nil, &list)
+	return list, err
+}
+
+// Функция сравнения двух снимков данных
+func (c *Client) HistoryDiff(ctx context.Context, from, to string) (SnapshotDiff, error) {
+	var diff SnapshotDiff
+	err := c.do(ctx, http.MethodGet, "/api/history/diff", url.Values{"from": {from}, "to": {to}}, nil, &diff)
+	return diff, err
+}
+
+// Функция получения отчета о качестве данных
+func (c *Client) Quality(ctx context.Context) (QualityReport, error) {
+	var report QualityReport
+	err := c.do(ctx, http.MethodGet, "/api/quality", nil, nil, &report)
+	return report, err
+}
+
+// Функция получения списка подписок на уведомления
+func (c *Client) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var list []Webhook
+	err := c.do(ctx, http.MethodGet, "/api/webhooks", nil, nil, &list)
+	return list, err
+}
+
+// Функция регистрации подписки на уведомления
+func (c *Client) CreateWebhook(ctx context.Context, req WebhookRequest) (Webhook, error) {
+	var hook Webhook
+	err := c.do(ctx, http.MethodPost, "/api/webhooks", nil, req, &hook)
+	return hook, err
+}
+
+// Функция удаления подписки на уведомления
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/webhooks/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Функция получения журнала доставки уведомлений
+func (c *Client) WebhookDeliveries(ctx context.Context) ([]WebhookDelivery, error) {
+	var list []WebhookDelivery
+	err := c.do(ctx, http.MethodGet, "/api/webhooks/deliveries", nil, nil, &list)
+	return list, err
+}
+
+var jsonFormat = url.Values{"format": {"json"}}
+
+// Функция получения состояния кэша
+func (c *Client) CacheState(ctx context.Context) (CacheState, error) {
+	var state CacheState
+	err := c.do(ctx, http.MethodGet, "/admin/cache", jsonFormat, nil, &state)
+	return state, err
+}
+
+// Функция принудительного обновления данных
+func (c *Client) Refresh(ctx context.Context) (CacheState, error) {
+	var state CacheState
+	err := c.do(ctx, http.MethodPost, "/admin/refresh", jsonFormat, nil, &state)
+	return state, err
+}
+
+// Функция возврата к предыдущему снимку данных
+func (c *Client) Rollback(ctx context.Context) (CacheState, error) {
+	var state CacheState
+	err := c.do(ctx, http.MethodPost, "/admin/rollback", jsonFormat, nil, &state)
+	return state, err
+}
+
+// Функция выполнения запроса GraphQL, поле data ответа разбирается в out.
+// Ошибки выполнения запроса возвращаются отдельно от ошибок транспорта.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) ([]GraphQLError, error) {
+	body := map[string]interface{}{"query": query}
+	if variables != nil {
+		body["variables"] = variables
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, "/graphql", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Ошибки разбора и проверки запроса приходят со статусом 400 в поле errors
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return nil, readAPIError(resp)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return result.Errors, err
+		}
+	}
+	return result.Errors, nil
+}
@@ -0,0 +1,180 @@
+package client
+
+import "time"
+
+// Типы ответов API, соответствуют схемам из /openapi.json
+
+// Группа
+type Band struct {
+	ID             int        `json:"id"`
+	Image          string     `json:"image"`
+	Name           string     `json:"name"`
+	Members        []string   `json:"members"`
+	CreationDate   int        `json:"creationDate"`
+	FirstAlbum     string     `json:"firstAlbum"`
+	FirstAlbumDate string     `json:"firstAlbumDate,omitempty"`
+	ConcertDates   string     `json:"concertDates"`
+	Locations      []string   `json:"locations"`
+	Concerts       []Concert  `json:"concerts"`
+	Tour           *TourStats `json:"tour,omitempty"`
+}
+
+// Концерт группы
+type Concert struct {
+	Location string `json:"location"`
+	Date     string `json:"date"`
+}
+
+// Подсказки для поиска
+type Search struct {
+	Names         []string `json:"names"`
+	CreationDates []int    `json:"creationDates"`
+	FirstAlbums   []string `json:"firstAlbums"`
+	Members       []string `json:"members"`
+	Locations     []string `json:"locations"`
+}
+
+// Страница списка групп
+type BandPage struct {
+	Items      []Band `json:"items"`
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	TotalPages int    `json:"totalPages"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Остановка тура
+type TourStop struct {
+	Location   string    `json:"location"`
+	Name       string    `json:"name"`
+	Country    string    `json:"country"`
+	Date       time.Time `json:"date"`
+	DistanceKm float64   `json:"distanceKm"`
+}
+
+// Статистика тура группы
+type TourStats struct {
+	Route            []TourStop `json:"route"`
+	Shows            int        `json:"shows"`
+	TotalDistanceKm  float64    `json:"totalDistanceKm"`
+	Countries        []string   `json:"countries"`
+	BusiestYear      int        `json:"busiestYear,omitempty"`
+	BusiestYearShows int        `json:"busiestYearShows,omitempty"`
+	AverageGapDays   float64    `json:"averageGapDays"`
+	InvalidDates     []string   `json:"invalidDates,omitempty"`
+}
+
+// Сохраненный снимок данных
+type SnapshotInfo struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Bands  int       `json:"bands"`
+}
+
+// Ссылка на группу
+type BandRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Изменения данных группы
+type BandChange struct {
+	BandRef
+	AddedMembers      []string  `json:"addedMembers,omitempty"`
+	RemovedMembers    []string  `json:"removedMembers,omitempty"`
+	AddedConcerts     []Concert `json:"addedConcerts,omitempty"`
+	CancelledConcerts []Concert `json:"cancelledConcerts,omitempty"`
+}
+
+// Разница между двумя снимками
+type SnapshotDiff struct {
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	AddedBands   []BandRef    `json:"addedBands"`
+	RemovedBands []BandRef    `json:"removedBands"`
+	Changed      []BandChange `json:"changed"`
+}
+
+// Замечание проверки данных
+type Issue struct {
+	Severity string `json:"severity"`
+	Source   string `json:"source"`
+	RecordID int    `json:"recordId"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Группа, не прошедшая проверку
+type QuarantinedBand struct {
+	Band   Band     `json:"band"`
+	Issues []string `json:"issues"`
+}
+
+// Отчет о качестве данных
+type QualityReport struct {
+	Time        time.Time         `json:"time"`
+	Source      string            `json:"source"`
+	Bands       int               `json:"bands"`
+	Relations   int               `json:"relations"`
+	Locations   int               `json:"locations"`
+	Errors      int               `json:"errors"`
+	Warnings    int               `json:"warnings"`
+	Issues      []Issue           `json:"issues"`
+	Quarantined []QuarantinedBand `json:"quarantined"`
+}
+
+// Параметры новой подписки на уведомления
+type WebhookRequest struct {
+	URL       string   `json:"url"`
+	BandIDs   []int    `json:"bandIds,omitempty"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+// Подписка на уведомления
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	BandIDs   []int     `json:"bandIds,omitempty"`
+	Locations []string  `json:"locations,omitempty"`
+	Secret    string    `json:"secret"`
+	Created   time.Time `json:"created"`
+}
+
+// Попытка доставки уведомления
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhookId"`
+	URL        string    `json:"url"`
+	Time       time.Time `json:"time"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+}
+
+// Ошибка обновления кэша
+type RefreshError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Состояние кэша
+type CacheState struct {
+	LastRefresh time.Time      `json:"lastRefresh"`
+	Source      string         `json:"source"`
+	Errors      []RefreshError `json:"errors"`
+	Bands       int            `json:"bands"`
+	Relations   int            `json:"relations"`
+	Locations   int            `json:"locations"`
+	HasPrevious bool           `json:"hasPrevious"`
+	PreviousAt  time.Time      `json:"previousAt,omitempty"`
+	Version     int64          `json:"version"`
+}
+
+// Ошибка выполнения запроса GraphQL
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
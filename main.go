@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"lzhuk/groupie-tracker/pkg"
 	"net/http"
@@ -55,6 +54,16 @@ func main() {
 	log.SetPrefix("Сформирована запись: ")
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 
+	// Команды командной строки: go run main.go <search|band|concerts|refresh|export> ...
+	// Без команды или с командой serve запускается сервер
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		if err := pkg.RunCommand(os.Args[1], os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Ошибка:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 
@@ -65,58 +74,36 @@ func main() {
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel() // Отложенный вызов cancel для освобождения ресурсов
 
-	// Проверяем наличие файла кеша с артистами и группами при запуске сервера
-	if _, err := os.Stat("cacheArtist.json"); err == nil {
-		// Если файл существует, загружаем данные из него в кеш
-		data, err := ioutil.ReadFile("cacheArtist.json")
-		if err != nil {
-			log.Println("Ошибка при чтении файла кэша с артистами и группами:", err)
-		} else {
-			err = json.Unmarshal(data, &pkg.ResponseData.Band)
-			if err != nil {
-				log.Println("Ошибка при декодировании данных  с артистами и группами из файла кэша:", err)
-			} else {
-				log.Println("Данные с артистами и группами из файла кэша успешно загружены")
-			}
-		}
-	} else {
-		log.Println("Файл с кэшем артистов и групп отсутствует")
+	// Открываем хранилище, при первом запуске в него переносятся файлы кэша
+	if err := pkg.OpenStorage(); err != nil {
+		log.Println("Ошибка при открытии хранилища:", err)
 	}
+	defer pkg.CloseStorage()
 
-	// Проверяем наличие файла кеша с связями при запуске сервера
-	if _, err := os.Stat("cacheRelation.json"); err == nil {
-		// Если файл существует, загружаем данные из него в кеш
-		data, err := ioutil.ReadFile("cacheRelation.json")
-		if err != nil {
-			log.Println("Ошибка при чтении файла кэша со связями:", err)
-		} else {
-			err = json.Unmarshal(data, &pkg.RelationInfo)
-			if err != nil {
-				log.Println("Ошибка при декодировании данных о связях из файла кэша:", err)
-			} else {
-				log.Println("Данные со связями из файла кэша успешно загружены")
-			}
+	// Загружаем данные из хранилища, а без него из файлов кэша
+	if err := pkg.LoadFromStorage(); err != nil {
+		log.Println("Данные в хранилище отсутствуют:", err)
+		if err := pkg.LoadCacheFromFiles(); err != nil {
+			log.Println("Файлы кэша отсутствуют или повреждены:", err)
 		}
-	} else {
-		log.Println("Файл со связями отсутствует")
 	}
 
-	// Проверяем наличие файла кеша с локациями при запуске сервера
-	if _, err := os.Stat("cacheLocation.json"); err == nil {
-		// Если файл существует, загружаем данные из него в кеш
-		data, err := ioutil.ReadFile("cacheLocation.json")
-		if err != nil {
-			log.Println("Ошибка при чтении файла кэша с локациями:", err)
-		} else {
-			err = json.Unmarshal(data, &pkg.RelationInfo)
-			if err != nil {
-				log.Println("Ошибка при декодировании данных о локациях из файла кэша:", err)
-			} else {
-				log.Println("Данные о локациях из файла кэша успешно загружены")
-			}
-		}
-	} else {
-		log.Println("Файл с локациями отсутствует")
+	// Загружаем подписки на уведомления
+	if err := pkg.LoadWebhooks(); err != nil {
+		log.Println("Ошибка при загрузке подписок на уведомления:", err)
+	}
+
+	// Загружаем пользователей и их сессии из хранилища
+	if err := pkg.LoadUsers(); err != nil {
+		log.Println("Ошибка при загрузке пользователей:", err)
+	}
+
+	// Загружаем кэш координат и подключаем онлайн-геокодер, если он задан
+	if err := pkg.LoadGeoCache(); err != nil {
+		log.Println("Ошибка при загрузке кэша координат:", err)
+	}
+	if geocoderURL := os.Getenv("GEOCODER_URL"); geocoderURL != "" {
+		pkg.SetGeocoder(pkg.NewNominatimGeocoder(geocoderURL))
 	}
 
 	// Запускаем отдельную горутину для проверки соединения с интернетом
@@ -125,6 +112,9 @@ func main() {
 	// Загрузка данных из API в базу данных и кэш
 	pkg.UpdateCache()
 
+	// Поиск координат для локаций, которых нет в справочнике
+	go pkg.GeocodeAllLocations()
+
 	// Обновление данных в кэше
 	go timerCache(ctx)
 
@@ -282,8 +272,72 @@ func Server() *http.Server {
 
 	Mux.HandleFunc("/band", pkg.BandHandler)
 
+	Mux.HandleFunc("/band/", pkg.BandCalendarHandler)
+
+	Mux.HandleFunc("/calendar.ics", pkg.CombinedCalendarHandler)
+
 	Mux.HandleFunc("/search", pkg.SearchHandler)
 
+	Mux.HandleFunc("/img/", pkg.ImageHandler)
+
+	Mux.HandleFunc("/export/", pkg.ExportHandler)
+
+	Mux.HandleFunc("/locations", pkg.LocationsHandler)
+
+	Mux.HandleFunc("/locations/", pkg.LocationsHandler)
+
+	Mux.HandleFunc("/members", pkg.MembersHandler)
+
+	Mux.HandleFunc("/members/", pkg.MembersHandler)
+
+	Mux.HandleFunc("/quality", pkg.QualityHandler)
+
+	Mux.HandleFunc("/api/quality", pkg.APIQualityHandler)
+
+	Mux.HandleFunc("/api/bands", pkg.APIBandsHandler)
+
+	Mux.HandleFunc("/api/band", pkg.APIBandHandler)
+
+	Mux.HandleFunc("/api/search", pkg.APISearchHandler)
+
+	Mux.HandleFunc("/api/suggestions", pkg.APISuggestionsHandler)
+
+	Mux.HandleFunc("/openapi.json", pkg.OpenAPIHandler)
+
+	Mux.HandleFunc("/graphql", pkg.GraphQLHandler)
+
+	Mux.HandleFunc("/events", pkg.EventsHandler)
+
+	Mux.HandleFunc("/register", pkg.RegisterHandler)
+
+	Mux.HandleFunc("/login", pkg.LoginHandler)
+
+	Mux.HandleFunc("/logout", pkg.LogoutHandler)
+
+	Mux.HandleFunc("/follow", pkg.FollowHandler)
+
+	Mux.HandleFunc("/my", pkg.MyHandler)
+
+	Mux.HandleFunc("/feed.atom", pkg.AtomFeedHandler)
+
+	Mux.HandleFunc("/feed.rss", pkg.RSSFeedHandler)
+
+	Mux.HandleFunc("/history", pkg.HistoryHandler)
+
+	Mux.HandleFunc("/api/history", pkg.APIHistoryHandler)
+
+	Mux.HandleFunc("/api/history/diff", pkg.APIHistoryDiffHandler)
+
+	Mux.HandleFunc("/api/webhooks", pkg.AdminAuth(pkg.WebhooksHandler))
+
+	Mux.HandleFunc("/api/webhooks/", pkg.AdminAuth(pkg.WebhookHandler))
+
+	Mux.HandleFunc("/admin/cache", pkg.AdminAuth(pkg.AdminCacheHandler))
+
+	Mux.HandleFunc("/admin/refresh", pkg.AdminAuth(pkg.AdminRefreshHandler))
+
+	Mux.HandleFunc("/admin/rollback", pkg.AdminAuth(pkg.AdminRollbackHandler))
+
 	fileServer := http.FileServer(http.Dir("web/static"))
 
 	Mux.Handle("/web/static/", http.StripPrefix("/web/static/", fileServer))
@@ -293,7 +347,7 @@ func Server() *http.Server {
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 90 * time.Second,
 		IdleTimeout:  120 * time.Second,
-		Handler:      Mux,
+		Handler:      pkg.SecurityHeaders(pkg.WithLocale(pkg.RateLimit(Mux))),
 	}
 	log.Println("Сервер успешно запущен")
 	fmt.Printf("Cервер успешно запущен: %s"+"\n", "http://localhost:8080")
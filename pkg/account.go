@@ -0,0 +1,278 @@
+package pkg
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Данные для страниц входа и регистрации
+type accountPage struct {
+	Register bool
+	Username string
+	Error    string
+}
+
+// Данные для персональной страницы
+type myPage struct {
+	User     User
+	Bands    []Band
+	Upcoming []UpcomingConcert
+}
+
+// Функция проверки, что запрос пришел по HTTPS (в том числе через прокси)
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// Функция проверки, что форма отправлена с этого же сайта
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Браузеры без заголовка Origin защищены cookie с SameSite=Lax
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// Функция установки cookie сессии
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Функция вывода страницы входа или регистрации
+func renderAccount(w http.ResponseWriter, r *http.Request, statusCode int, page accountPage) {
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+	err = templates.ExecuteTemplate(w, "account.html", &page)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Функция получения текста ошибки формы для пользователя
+func accountErrorMessage(locale string, err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidUsername):
+		return Translate(locale, "account.error.username")
+	case errors.Is(err, ErrWeakPassword):
+		return Translate(locale, "account.error.weak_password", minPasswordLength)
+	case errors.Is(err, ErrUserExists):
+		return Translate(locale, "account.error.user_exists")
+	case errors.Is(err, ErrInvalidCredentials):
+		return Translate(locale, "account.error.credentials")
+	case errors.Is(err, ErrUserStorageUnavailable):
+		return Translate(locale, "account.error.unavailable")
+	default:
+		return Translate(locale, "account.error.generic")
+	}
+}
+
+// Функция входа пользователя после регистрации или проверки пароля
+func startSession(w http.ResponseWriter, r *http.Request, user User) {
+	token, err := CreateSession(user.ID)
+	if errors.Is(err, ErrUserStorageUnavailable) {
+		log.Println(err)
+		renderAccount(w, r, http.StatusServiceUnavailable, accountPage{Error: accountErrorMessage(RequestLocale(r), err)})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	setSessionCookie(w, r, token, int(sessionLifetime/time.Second))
+	http.Redirect(w, r, "/my", http.StatusSeeOther)
+}
+
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/register" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		renderAccount(w, r, http.StatusOK, accountPage{Register: true})
+	case http.MethodPost:
+		if !sameOrigin(r) {
+			ErrorHandler(w, r, http.StatusForbidden)
+			return
+		}
+
+		username := r.PostFormValue("username")
+		user, err := RegisterUser(username, r.PostFormValue("password"))
+		if err != nil {
+			log.Println("Ошибка регистрации:", err)
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrUserStorageUnavailable) {
+				status = http.StatusServiceUnavailable
+			}
+			renderAccount(w, r, status, accountPage{Register: true, Username: username, Error: accountErrorMessage(RequestLocale(r), err)})
+			return
+		}
+
+		startSession(w, r, user)
+	default:
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+	}
+}
+
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/login" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		renderAccount(w, r, http.StatusOK, accountPage{})
+	case http.MethodPost:
+		if !sameOrigin(r) {
+			ErrorHandler(w, r, http.StatusForbidden)
+			return
+		}
+
+		username := r.PostFormValue("username")
+		user, err := AuthenticateUser(username, r.PostFormValue("password"))
+		if err != nil {
+			log.Println("Неудачная попытка входа пользователя", username, "с адреса", r.RemoteAddr)
+			renderAccount(w, r, http.StatusUnauthorized, accountPage{Username: username, Error: accountErrorMessage(RequestLocale(r), err)})
+			return
+		}
+
+		startSession(w, r, user)
+	default:
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+	}
+}
+
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/logout" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !sameOrigin(r) {
+		ErrorHandler(w, r, http.StatusForbidden)
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := DeleteSession(cookie.Value); err != nil {
+			log.Println(err)
+		}
+	}
+
+	setSessionCookie(w, r, "", -1)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func FollowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/follow" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !sameOrigin(r) {
+		ErrorHandler(w, r, http.StatusForbidden)
+		return
+	}
+
+	user, ok := CurrentUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	numID, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := bandByID(numID); !ok {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if err := SetFollow(user.ID, numID, r.PostFormValue("action") != "unfollow"); err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/band?id="+strconv.Itoa(numID), http.StatusSeeOther)
+}
+
+func MyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/my" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := CurrentUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	page := myPage{User: user}
+
+	bandInfoMu.RLock()
+	for _, b := range ResponseData.Band {
+		if user.IsFollowing(b.ID) {
+			page.Bands = append(page.Bands, b)
+		}
+	}
+	page.Upcoming = UpcomingConcerts(ResponseData.Band, user.Follows, time.Now())
+	bandInfoMu.RUnlock()
+
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+	err = templates.ExecuteTemplate(w, "my.html", &page)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
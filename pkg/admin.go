@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+)
+
+// Функция проверки доступа к административной панели (HTTP Basic Auth).
+// Логин и пароль задаются переменными окружения ADMIN_USER и ADMIN_PASSWORD,
+// без пароля административная панель отключена.
+func AdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantUser := os.Getenv("ADMIN_USER")
+		wantPassword := os.Getenv("ADMIN_PASSWORD")
+		if wantPassword == "" {
+			ErrorHandler(w, r, http.StatusNotFound)
+			return
+		}
+		if wantUser == "" {
+			wantUser = "admin"
+		}
+
+		user, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(wantUser)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(wantPassword)) != 1 {
+			log.Println("Неудачная попытка входа в административную панель с адреса", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Basic realm="groupie-tracker admin", charset="UTF-8"`)
+			ErrorHandler(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func AdminCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/cache" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := GetCacheState()
+
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, http.StatusOK, state)
+		return
+	}
+
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+	err = templates.ExecuteTemplate(w, "admin.html", &state)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
+
+func AdminRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/refresh" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Println("Принудительное обновление кэша из административной панели")
+
+	err := UpdateCache()
+
+	adminRespond(w, r, err, http.StatusBadGateway)
+}
+
+func AdminRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/rollback" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := RollbackCache()
+	if err != nil {
+		log.Println(err)
+	}
+
+	adminRespond(w, r, err, http.StatusConflict)
+}
+
+// Функция ответа на действие администратора: JSON для API или возврат на страницу состояния
+func adminRespond(w http.ResponseWriter, r *http.Request, err error, errStatus int) {
+	if r.URL.Query().Get("format") == "json" {
+		if err != nil {
+			writeJSON(w, errStatus, map[string]string{"error": LocalizeError(RequestLocale(r), err)})
+			return
+		}
+		writeJSON(w, http.StatusOK, GetCacheState())
+		return
+	}
+
+	if err != nil {
+		ErrorHandler(w, r, errStatus)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/cache", http.StatusSeeOther)
+}
@@ -3,6 +3,7 @@ package pkg
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 )
 
 type Data struct {
@@ -20,6 +21,8 @@ type Band struct {
 	Locations    []string            `json:"-"`
 	ConcertDates string              `json:"concertDates"`
 	Relations    map[string][]string `json:"-"`
+	// Дата первого альбома, разобранная при загрузке данных
+	FirstAlbumDate time.Time `json:"-"`
 }
 
 type Relations struct {
@@ -38,11 +41,11 @@ type Location struct {
 }
 
 type Search struct {
-	Names         []string
-	CreationDates []int
-	FirstAlbums   []string
-	Members       []string
-	Locations     []string
+	Names         []string `json:"names"`
+	CreationDates []int    `json:"creationDates"`
+	FirstAlbums   []string `json:"firstAlbums"`
+	Members       []string `json:"members"`
+	Locations     []string `json:"locations"`
 }
 
 func GetBandInfo(ArtistAPI string) ([]Band, error) {
@@ -0,0 +1,243 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	SourceAPI  = "api"
+	SourceFile = "file"
+
+	cacheArtistFile   = "cacheArtist.json"
+	cacheRelationFile = "cacheRelation.json"
+	cacheLocationFile = "cacheLocation.json"
+
+	maxRefreshErrors = 20
+)
+
+// Снимок данных, полученных за одно обновление кэша
+type Snapshot struct {
+	Bands     []Band    `json:"bands"`
+	Relations Relations `json:"relations"`
+	Locations Location  `json:"locations"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+}
+
+// Ошибка, возникшая при обновлении кэша
+type RefreshError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Состояние кэша для административной панели
+type CacheState struct {
+	LastRefresh time.Time      `json:"lastRefresh"`
+	Source      string         `json:"source"`
+	Errors      []RefreshError `json:"errors"`
+	Bands       int            `json:"bands"`
+	Relations   int            `json:"relations"`
+	Locations   int            `json:"locations"`
+	HasPrevious bool           `json:"hasPrevious"`
+	PreviousAt  time.Time      `json:"previousAt,omitempty"`
+	Version     int64          `json:"version"`
+}
+
+var (
+	cacheState       CacheState
+	snapshotVersion  int64
+	previousSnapshot *Snapshot
+	cacheStateMu     sync.Mutex
+
+	ErrNoPreviousSnapshot = errors.New("предыдущий снимок данных отсутствует")
+
+	refreshListeners   []RefreshListener
+	refreshListenersMu sync.Mutex
+)
+
+// Обработчик изменений данных после обновления кэша
+type RefreshListener func(diff SnapshotDiff, current Snapshot)
+
+// Функция подписки на изменения данных после обновления кэша
+func OnRefresh(listener RefreshListener) {
+	refreshListenersMu.Lock()
+	defer refreshListenersMu.Unlock()
+
+	refreshListeners = append(refreshListeners, listener)
+}
+
+// Функция получения версии данных, версия растет при каждом изменении данных
+func SnapshotVersion() int64 {
+	cacheStateMu.Lock()
+	defer cacheStateMu.Unlock()
+
+	return snapshotVersion
+}
+
+// Функция оповещения подписчиков об изменениях между снимками
+func publishChanges(previous *Snapshot, current Snapshot) {
+	if previous == nil {
+		cacheStateMu.Lock()
+		snapshotVersion++
+		cacheStateMu.Unlock()
+		return
+	}
+
+	diff := DiffSnapshots(*previous, current)
+	if diff.Empty() {
+		return
+	}
+
+	cacheStateMu.Lock()
+	snapshotVersion++
+	cacheStateMu.Unlock()
+
+	refreshListenersMu.Lock()
+	listeners := append([]RefreshListener(nil), refreshListeners...)
+	refreshListenersMu.Unlock()
+
+	for _, listener := range listeners {
+		listener(diff, current)
+	}
+}
+
+// Функция записи ошибки обновления в историю
+func recordRefreshError(err error) {
+	cacheStateMu.Lock()
+	defer cacheStateMu.Unlock()
+
+	cacheState.Errors = append(cacheState.Errors, RefreshError{Time: time.Now(), Message: err.Error()})
+	if len(cacheState.Errors) > maxRefreshErrors {
+		cacheState.Errors = cacheState.Errors[len(cacheState.Errors)-maxRefreshErrors:]
+	}
+}
+
+// Функция получения текущего состояния кэша
+func GetCacheState() CacheState {
+	bandInfoMu.RLock()
+	relationInfoMu.RLock()
+	locationInfoMu.RLock()
+	cacheStateMu.Lock()
+
+	state := cacheState
+	state.Errors = append([]RefreshError(nil), cacheState.Errors...)
+	state.Bands = len(BandInfo)
+	state.Relations = len(RelationInfo.Index)
+	state.Locations = len(LocationInfo.Index)
+	state.Version = snapshotVersion
+	if previousSnapshot != nil {
+		state.HasPrevious = true
+		state.PreviousAt = previousSnapshot.Time
+	}
+
+	cacheStateMu.Unlock()
+	locationInfoMu.RUnlock()
+	relationInfoMu.RUnlock()
+	bandInfoMu.RUnlock()
+
+	return state
+}
+
+// Функция отката кэша к предыдущему снимку данных
+func RollbackCache() error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	cacheStateMu.Lock()
+	prev := previousSnapshot
+	previousSnapshot = nil
+	cacheStateMu.Unlock()
+
+	if prev == nil {
+		return ErrNoPreviousSnapshot
+	}
+
+	current := currentSnapshot()
+	applySnapshot(*prev)
+	persistSnapshot(*prev)
+	publishChanges(current, *prev)
+
+	log.Println("Кэш восстановлен из предыдущего снимка")
+
+	return nil
+}
+
+// Функция загрузки данных из файлов кэша
+func LoadCacheFromFiles() error {
+	var s Snapshot
+
+	if err := readCacheFile(cacheArtistFile, &s.Bands); err != nil {
+		log.Println("Ошибка при загрузке данных с артистами и группами из файла кэша:", err)
+		return err
+	}
+
+	if err := readCacheFile(cacheRelationFile, &s.Relations); err != nil {
+		log.Println("Ошибка при загрузке данных о связях из файла кэша:", err)
+		return err
+	}
+
+	if err := readCacheFile(cacheLocationFile, &s.Locations); err != nil {
+		log.Println("Ошибка при загрузке данных о локациях из файла кэша:", err)
+		return err
+	}
+
+	s.Source = SourceFile
+	if info, err := os.Stat(cacheArtistFile); err == nil {
+		s.Time = info.ModTime()
+	}
+
+	s, report := ValidateSnapshot(s)
+	setQualityReport(report)
+
+	refreshMu.Lock()
+	previous := currentSnapshot()
+	applySnapshot(s)
+	publishChanges(previous, s)
+	refreshMu.Unlock()
+
+	log.Println("Данные из файлов кэша успешно загружены")
+
+	return nil
+}
+
+// Функция сохранения текущих данных в файлы кэша
+func SaveCacheFiles() error {
+	current := currentSnapshot()
+	if current == nil {
+		return errors.New("данные не загружены")
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{cacheArtistFile, current.Bands},
+		{cacheRelationFile, current.Relations},
+		{cacheLocationFile, current.Locations},
+	}
+	for _, f := range files {
+		data, err := json.Marshal(f.v)
+		if err != nil {
+			return err
+		}
+		if err := SaveCacheToFile(f.name, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readCacheFile(filename string, v interface{}) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,203 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	icsProdID   = "-//groupie-tracker//concerts//EN"
+	icsUIDHost  = "groupie-tracker"
+	icsMaxOctet = 75
+
+	icsRefreshInterval = "PT1H"
+	icsFeedMaxAge      = time.Hour
+	maxFeedBands       = 50
+)
+
+// Функция получения постоянного идентификатора концерта, чтобы календари не дублировали события
+func ConcertUID(bandID int, c Concert) string {
+	sum := sha1.Sum([]byte(strconv.Itoa(bandID) + "|" + c.Location + "|" + c.Date))
+	return hex.EncodeToString(sum[:]) + "@" + icsUIDHost
+}
+
+// Функция экранирования текста для iCalendar
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// Функция записи строки iCalendar с переносом длинных строк (не более 75 байт)
+func icsLine(w io.Writer, line string) error {
+	for len(line) > icsMaxOctet {
+		cut := icsMaxOctet
+		// Не разрезаем многобайтовые символы UTF-8
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, err := io.WriteString(w, line[:cut]+"\r\n"); err != nil {
+			return err
+		}
+		line = " " + line[cut:]
+	}
+	_, err := io.WriteString(w, line+"\r\n")
+	return err
+}
+
+// Функция записи календаря концертов групп в формате iCalendar
+func WriteICS(w io.Writer, name string, bands []Band, stamp time.Time) error {
+	return writeICS(w, name, bands, stamp, nil)
+}
+
+// Функция записи календаря для подписки: клиенты обновляют его раз в час
+func WriteICSFeed(w io.Writer, name string, bands []Band, stamp time.Time) error {
+	return writeICS(w, name, bands, stamp, []string{
+		"REFRESH-INTERVAL;VALUE=DURATION:" + icsRefreshInterval,
+		"X-PUBLISHED-TTL:" + icsRefreshInterval,
+	})
+}
+
+func writeICS(w io.Writer, name string, bands []Band, stamp time.Time, extra []string) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + icsProdID,
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icsEscape(name),
+	}
+	lines = append(lines, extra...)
+
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+	for _, b := range bands {
+		for _, c := range ConcertsFromRelations(b.Relations) {
+			date, err := ParseConcertDate(c.Date)
+			if err != nil {
+				continue
+			}
+			lines = append(lines,
+				"BEGIN:VEVENT",
+				"UID:"+ConcertUID(b.ID, c),
+				"DTSTAMP:"+dtstamp,
+				"DTSTART;VALUE=DATE:"+date.Format("20060102"),
+				"DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format("20060102"),
+				"SUMMARY:"+icsEscape(fmt.Sprintf("%s - %s", b.Name, LocationName(c.Location))),
+				"LOCATION:"+icsEscape(LocationName(c.Location)),
+				"END:VEVENT",
+			)
+		}
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if err := icsLine(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Функция отправки календаря для подписки с поддержкой условных запросов
+func serveCalendarFeed(w http.ResponseWriter, r *http.Request, name string, bands []Band) {
+	state := GetCacheState()
+
+	var buf bytes.Buffer
+	if err := WriteICSFeed(&buf, name, bands, state.LastRefresh); err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(icsFeedMaxAge.Seconds())))
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	http.ServeContent(w, r, "calendar.ics", state.LastRefresh, bytes.NewReader(buf.Bytes()))
+}
+
+// Обработчик календаря группы: /band/{id}/calendar.ics
+func BandCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/band/")
+	id, file, ok := strings.Cut(rest, "/")
+	if !ok || file != "calendar.ics" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	numID, err := strconv.Atoi(id)
+	if err != nil {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	band, found := bandByID(numID)
+	if !found {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	serveCalendarFeed(w, r, band.Name+" concerts", []Band{band})
+}
+
+// Обработчик общего календаря нескольких групп: /calendar.ics?bands=1,2,3
+func CombinedCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/calendar.ics" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	ids, err := parseBandIDs(r.URL.Query().Get("bands"))
+	if err != nil || len(ids) == 0 || len(ids) > maxFeedBands {
+		ErrorHandler(w, r, http.StatusBadRequest)
+		return
+	}
+
+	var bands []Band
+	for _, id := range ids {
+		if band, ok := bandByID(id); ok {
+			bands = append(bands, band)
+		}
+	}
+	if len(bands) == 0 {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	serveCalendarFeed(w, r, "Followed bands concerts", bands)
+}
+
+// Функция разбора списка номеров групп вида "1,2,3" без повторов
+func parseBandIDs(s string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный номер группы %q", part)
+		}
+		if !repeatInt(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Стили форматирования дат в терминах CLDR
+const (
+	DateLong   = "long"
+	DateMedium = "medium"
+	DateShort  = "short"
+)
+
+// Данные языка в формате, повторяющем структуру CLDR: названия месяцев,
+// шаблоны дат и времени и названия стран по кодам ISO 3166
+type localeData struct {
+	Months struct {
+		Abbreviated []string `json:"abbreviated"`
+		Wide        []string `json:"wide"`
+	} `json:"months"`
+	DayPeriods struct {
+		AM string `json:"am"`
+		PM string `json:"pm"`
+	} `json:"dayPeriods"`
+	DateFormats    map[string]string `json:"dateFormats"`
+	TimeFormats    map[string]string `json:"timeFormats"`
+	DateTimeFormat string            `json:"dateTimeFormat"`
+	Territories    map[string]string `json:"territories"`
+}
+
+// Данные CLDR для поддерживаемых языков
+var cldr = loadLocaleData()
+
+// Функция загрузки встроенных данных CLDR
+func loadLocaleData() map[string]*localeData {
+	result := make(map[string]*localeData, len(supportedLocales))
+	for _, locale := range supportedLocales {
+		raw, err := localeFiles.ReadFile("locales/cldr/" + locale + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("данные CLDR для %s не найдены: %v", locale, err))
+		}
+		data := &localeData{}
+		if err := json.Unmarshal(raw, data); err != nil {
+			panic(fmt.Sprintf("данные CLDR для %s повреждены: %v", locale, err))
+		}
+		if len(data.Months.Wide) != 12 || len(data.Months.Abbreviated) != 12 {
+			panic(fmt.Sprintf("данные CLDR для %s: нужно 12 названий месяцев", locale))
+		}
+		result[locale] = data
+	}
+	return result
+}
+
+// Функция получения данных языка, для неизвестного языка - данных по умолчанию
+func localeDataFor(locale string) *localeData {
+	if data, ok := cldr[locale]; ok {
+		return data
+	}
+	return cldr[DefaultLocale]
+}
+
+// Функция форматирования даты по шаблону CLDR. Поддерживаются поля
+// d, dd, M, MM, MMM, MMMM, y, yy, yyyy, H, HH, h, hh, mm, ss, a и текст в кавычках.
+func formatPattern(data *localeData, pattern string, t time.Time) string {
+	var b strings.Builder
+	runes := []rune(pattern)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		if c == '\'' {
+			// '' - это одиночная кавычка, остальное в кавычках выводится как есть
+			if i+1 < len(runes) && runes[i+1] == '\'' {
+				b.WriteRune('\'')
+				i += 2
+				continue
+			}
+			i++
+			for i < len(runes) && runes[i] != '\'' {
+				b.WriteRune(runes[i])
+				i++
+			}
+			i++
+			continue
+		}
+
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			b.WriteRune(c)
+			i++
+			continue
+		}
+
+		n := 1
+		for i+n < len(runes) && runes[i+n] == c {
+			n++
+		}
+		i += n
+
+		switch c {
+		case 'd':
+			b.WriteString(pad(t.Day(), n))
+		case 'M':
+			switch {
+			case n >= 4:
+				b.WriteString(data.Months.Wide[t.Month()-1])
+			case n == 3:
+				b.WriteString(data.Months.Abbreviated[t.Month()-1])
+			default:
+				b.WriteString(pad(int(t.Month()), n))
+			}
+		case 'y':
+			if n == 2 {
+				b.WriteString(pad(t.Year()%100, 2))
+			} else {
+				b.WriteString(pad(t.Year(), n))
+			}
+		case 'H':
+			b.WriteString(pad(t.Hour(), n))
+		case 'h':
+			hour := t.Hour() % 12
+			if hour == 0 {
+				hour = 12
+			}
+			b.WriteString(pad(hour, n))
+		case 'm':
+			b.WriteString(pad(t.Minute(), n))
+		case 's':
+			b.WriteString(pad(t.Second(), n))
+		case 'a':
+			if t.Hour() < 12 {
+				b.WriteString(data.DayPeriods.AM)
+			} else {
+				b.WriteString(data.DayPeriods.PM)
+			}
+		default:
+			b.WriteString(strings.Repeat(string(c), n))
+		}
+	}
+
+	return b.String()
+}
+
+// Функция дополнения числа нулями слева до нужной ширины
+func pad(n, width int) string {
+	s := strconv.Itoa(n)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+// Функция форматирования даты на языке locale в стиле long, medium или short
+func FormatDate(locale string, t time.Time, style string) string {
+	data := localeDataFor(locale)
+	pattern, ok := data.DateFormats[style]
+	if !ok {
+		pattern = data.DateFormats[DateMedium]
+	}
+	return formatPattern(data, pattern, t)
+}
+
+// Функция форматирования даты и времени на языке locale
+func FormatDateTime(locale string, t time.Time) string {
+	data := localeDataFor(locale)
+	date := formatPattern(data, data.DateFormats[DateMedium], t)
+	clock := formatPattern(data, data.TimeFormats[DateShort], t)
+	return strings.NewReplacer("{1}", date, "{0}", clock).Replace(data.DateTimeFormat)
+}
+
+// Функция форматирования даты концерта или первого альбома вида "dd-mm-yyyy".
+// Дата, которую не удалось разобрать, возвращается без изменений.
+func FormatConcertDate(locale, date string, style string) string {
+	t, err := ParseConcertDate(date)
+	if err != nil {
+		return date
+	}
+	return FormatDate(locale, t, style)
+}
+
+// Функция получения названия страны на языке locale по ключу из API ("new_zealand").
+// Для стран без кода или перевода используется название из ключа.
+func CountryName(locale, key string) string {
+	if code, ok := countryCodes[key]; ok {
+		if name, ok := localeDataFor(locale).Territories[code]; ok {
+			return name
+		}
+		if name, ok := cldr[DefaultLocale].Territories[code]; ok {
+			return name
+		}
+	}
+	return HumanizeKey(key)
+}
+
+// Функция получения названия локации на языке locale: "Berlin, Германия"
+func PlaceName(locale, location string) string {
+	place, country := ParseLocation(location)
+	if place == "" {
+		return CountryName(locale, country)
+	}
+	return HumanizeKey(place) + ", " + CountryName(locale, country)
+}
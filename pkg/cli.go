@@ -0,0 +1,421 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var ErrUnknownCommand = errors.New("неизвестная команда")
+
+// Команды командной строки, команда serve (запуск сервера) обрабатывается в main
+var cliCommands = map[string]func(args []string, stdout io.Writer) error{
+	"search":   runSearch,
+	"band":     runBand,
+	"concerts": runConcerts,
+	"browse":   runBrowse,
+	"refresh":  runRefresh,
+	"export":   RunExport,
+}
+
+const cliUsage = `Использование: groupie-tracker <команда> [аргументы]
+
+Команды:
+  serve                          запуск веб-сервера (по умолчанию)
+  search <запрос> [-json]        поиск групп
+  band <id> [-json]              группа и ее концерты
+  concerts [-country=<страна>]   концерты, при необходимости в одной стране
+  browse                         терминальный интерфейс для просмотра групп
+  refresh                        загрузка данных из API в хранилище и файлы кэша
+  export <формат> [-o file]      выгрузка данных (bands.csv, members.csv, concerts.csv, bands.jsonl, band.ics)
+
+Команды search, band, concerts и browse принимают -offline, чтобы читать только локальный кэш.`
+
+// Функция выполнения команды командной строки
+func RunCommand(name string, args []string, stdout io.Writer) error {
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprintln(stdout, cliUsage)
+		return nil
+	}
+
+	run, ok := cliCommands[name]
+	if !ok {
+		return fmt.Errorf("%w: %q\n\n%s", ErrUnknownCommand, name, cliUsage)
+	}
+	return run(args, stdout)
+}
+
+// Общие параметры команд чтения данных
+type cliOptions struct {
+	json    bool
+	offline bool
+}
+
+// Функция создания набора флагов команды с общими параметрами
+func commandFlags(name, usage string, opts *cliOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.BoolVar(&opts.json, "json", false, "вывод в формате JSON")
+	fs.BoolVar(&opts.offline, "offline", false, "не обращаться к API, читать хранилище или файлы кэша")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование:", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// Функция разбора флагов, которые могут стоять как до, так и после аргументов
+func parseCommandArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// Функция загрузки данных для команды
+func (o cliOptions) load() error {
+	if o.offline {
+		return LoadLocalData()
+	}
+	return LoadData()
+}
+
+// Функция загрузки данных для команд командной строки: из API, а без интернета
+// из хранилища (только для чтения, его может использовать запущенный сервер) или файлов кэша.
+// Данные из API ничего не записывают на диск: для этого есть команда refresh.
+func LoadData() error {
+	if err := FetchData(); err != nil {
+		if errLocal := LoadLocalData(); errLocal != nil {
+			return fmt.Errorf("данные недоступны: %v; кэш: %v", err, errLocal)
+		}
+	}
+	return nil
+}
+
+// Функция загрузки данных без обращения к API: из хранилища, а без него из файлов кэша
+func LoadLocalData() error {
+	if err := loadFromDBFile(); err == nil {
+		return nil
+	}
+	return LoadCacheFromFiles()
+}
+
+// Функция загрузки данных из файла хранилища без его изменения
+func loadFromDBFile() error {
+	repo, err := OpenRepository(dbFile(), true)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	return loadFromRepository(repo)
+}
+
+// Функция вывода значения в формате JSON с отступами
+func writeCLIJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Функция выполнения команды search: search <запрос> [-json] [-offline]
+func runSearch(args []string, stdout io.Writer) error {
+	var opts cliOptions
+	fs := commandFlags("search", "search <запрос> [-json] [-offline]", &opts)
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	query := strings.Join(positional, " ")
+	if query == "" {
+		fs.Usage()
+		return errors.New("запрос не указан")
+	}
+
+	if err := opts.load(); err != nil {
+		return err
+	}
+
+	// Пустой результат поиска - не ошибка, как и в API
+	var bands []Band
+	if found, err := SearchRecords(SnapshotBands(), query); err == nil {
+		bands = *found
+	}
+
+	if opts.json {
+		list := make([]APIBand, 0, len(bands))
+		for _, b := range bands {
+			list = append(list, newAPIBand(b, false))
+		}
+		return writeCLIJSON(stdout, list)
+	}
+
+	if len(bands) == 0 {
+		fmt.Fprintf(stdout, "По запросу %q ничего не найдено\n", query)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tНАЗВАНИЕ\tСОЗДАНА\tПЕРВЫЙ АЛЬБОМ\tУЧАСТНИКИ")
+	for _, b := range bands {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%d\n", b.ID, b.Name, b.CreationDate, b.FirstAlbum, len(b.Members))
+	}
+	return tw.Flush()
+}
+
+// Функция выполнения команды band: band <id> [-json] [-offline]
+func runBand(args []string, stdout io.Writer) error {
+	var opts cliOptions
+	fs := commandFlags("band", "band <id> [-json] [-offline]", &opts)
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("укажите номер группы")
+	}
+	id, err := strconv.Atoi(positional[0])
+	if err != nil {
+		return fmt.Errorf("некорректный номер группы %q", positional[0])
+	}
+
+	if err := opts.load(); err != nil {
+		return err
+	}
+
+	band, ok := bandByID(id)
+	if !ok {
+		return fmt.Errorf("группа %d не найдена", id)
+	}
+
+	if opts.json {
+		return writeCLIJSON(stdout, newAPIBand(band, true))
+	}
+
+	concerts := ListConcerts([]Band{band}, "")
+
+	fmt.Fprintf(stdout, "%s (%d)\n", band.Name, band.ID)
+	if err := writeBandSummary(stdout, band, concerts); err != nil {
+		return err
+	}
+
+	if len(concerts) == 0 {
+		return nil
+	}
+	fmt.Fprintln(stdout)
+	return writeConcertsTable(stdout, concerts, false)
+}
+
+// Функция вывода сведений о группе
+func writeBandSummary(w io.Writer, band Band, concerts []ConcertListing) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Участники:\t%s\n", strings.Join(band.Members, ", "))
+	fmt.Fprintf(tw, "Создана:\t%d\n", band.CreationDate)
+	fmt.Fprintf(tw, "Первый альбом:\t%s\n", band.FirstAlbum)
+	fmt.Fprintf(tw, "Концерты:\t%d\n", len(concerts))
+	return tw.Flush()
+}
+
+// Функция выполнения команды concerts: concerts [-country=<страна>] [-json] [-offline]
+func runConcerts(args []string, stdout io.Writer) error {
+	var opts cliOptions
+	fs := commandFlags("concerts", "concerts [-country=<страна>] [-json] [-offline]", &opts)
+	country := fs.String("country", "", "страна: ключ из данных (germany, usa) или название (New Zealand)")
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("лишние аргументы: %v", positional)
+	}
+
+	if err := opts.load(); err != nil {
+		return err
+	}
+
+	concerts := ListConcerts(SnapshotBands(), *country)
+
+	if opts.json {
+		return writeCLIJSON(stdout, concerts)
+	}
+
+	if len(concerts) == 0 {
+		fmt.Fprintln(stdout, "Концерты не найдены")
+		return nil
+	}
+	return writeConcertsTable(stdout, concerts, true)
+}
+
+// Функция выполнения команды refresh: загрузка данных из API в хранилище и файлы кэша.
+// Если хранилище открыто запущенным сервером, команда завершается ошибкой.
+func runRefresh(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("refresh", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: refresh")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := OpenStorage(); err != nil {
+		if errors.Is(err, ErrStoreLocked) {
+			return fmt.Errorf("%w: остановите сервер или используйте /admin/refresh", err)
+		}
+		return err
+	}
+	defer CloseStorage()
+
+	if err := UpdateCache(); err != nil {
+		return err
+	}
+	if err := SaveCacheFiles(); err != nil {
+		return err
+	}
+
+	state := GetCacheState()
+	report := GetQualityReport()
+	fmt.Fprintf(stdout, "Данные обновлены: групп %d, связей %d, локаций %d (ошибок %d, предупреждений %d)\n",
+		state.Bands, state.Relations, state.Locations, report.Errors, report.Warnings)
+
+	return nil
+}
+
+// Концерт в списке концертов
+type ConcertListing struct {
+	BandID   int    `json:"bandId"`
+	Band     string `json:"band"`
+	Location string `json:"location"`
+	Place    string `json:"place"`
+	Date     string `json:"date"`
+}
+
+// Функция приведения страны из запроса к ключу из данных: "New Zealand" -> "new_zealand"
+func countryKey(country string) string {
+	return strings.Join(strings.Fields(strings.ToLower(country)), "_")
+}
+
+// Функция получения концертов групп в хронологическом порядке.
+// Если страна указана, возвращаются только концерты в ней.
+func ListConcerts(bands []Band, country string) []ConcertListing {
+	key := countryKey(country)
+
+	var list []ConcertListing
+	for _, b := range bands {
+		for _, c := range ConcertsFromRelations(b.Relations) {
+			if _, bandCountry := ParseLocation(c.Location); key != "" && bandCountry != key {
+				continue
+			}
+			list = append(list, ConcertListing{
+				BandID:   b.ID,
+				Band:     b.Name,
+				Location: c.Location,
+				Place:    LocationName(c.Location),
+				Date:     c.Date,
+			})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, errA := ParseConcertDate(list[i].Date)
+		b, errB := ParseConcertDate(list[j].Date)
+		if errA != nil || errB != nil {
+			if (errA == nil) != (errB == nil) {
+				return errA == nil
+			}
+		} else if !a.Equal(b) {
+			return a.Before(b)
+		}
+		if list[i].Band != list[j].Band {
+			return list[i].Band < list[j].Band
+		}
+		return list[i].Location < list[j].Location
+	})
+
+	if list == nil {
+		list = []ConcertListing{}
+	}
+	return list
+}
+
+// Функция вывода концертов таблицей
+func writeConcertsTable(w io.Writer, concerts []ConcertListing, withBand bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withBand {
+		fmt.Fprintln(tw, "ДАТА\tГРУППА\tМЕСТО")
+	} else {
+		fmt.Fprintln(tw, "ДАТА\tМЕСТО")
+	}
+	for _, c := range concerts {
+		if withBand {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Date, c.Band, c.Place)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\n", c.Date, c.Place)
+		}
+	}
+	return tw.Flush()
+}
+
+// Функция выполнения команды export: export <format> [-o file] [-id N]
+func RunExport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "", "файл для выгрузки (по умолчанию стандартный вывод)")
+	id := fs.Int("id", 0, "номер группы для формата band.ics")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: export <bands.csv|members.csv|concerts.csv|bands.jsonl|band.ics> [-o file] [-id N]")
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: формат не указан", ErrUnknownExportFormat)
+	}
+	format := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if _, ok := ExportFormats[format]; !ok && format != "band.ics" {
+		return fmt.Errorf("%w: %q", ErrUnknownExportFormat, format)
+	}
+
+	if err := LoadData(); err != nil {
+		return err
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "band.ics" {
+		band, ok := bandByID(*id)
+		if !ok {
+			return fmt.Errorf("группа %d не найдена", *id)
+		}
+		return WriteICS(w, band.Name, []Band{band}, time.Now())
+	}
+
+	return Export(w, format, SnapshotBands())
+}
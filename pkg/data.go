@@ -1,12 +1,16 @@
 package pkg
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -21,6 +25,7 @@ var (
 	RelationInfo                               Relations
 	LocationInfo                               Location
 	bandInfoMu, relationInfoMu, locationInfoMu sync.RWMutex
+	refreshMu                                  sync.Mutex
 )
 
 func SaveCacheToFile(filename string, data []byte) error {
@@ -39,59 +44,194 @@ func SaveCacheToFile(filename string, data []byte) error {
 	return nil
 }
 
-func UpdateCache() error {
-	var err error
-
-	newBandInfo, err := GetBandInfo(artistAPI)
+// Функция записи файла целиком: данные пишутся во временный файл в том же каталоге
+// и переименовываются, чтобы параллельный запрос не прочитал частично записанный файл,
+// а сбой во время записи не испортил прежнее содержимое
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
-		log.Println(err.Error())
 		return err
 	}
+	defer os.Remove(tmp.Name())
 
-	bandInfoMu.Lock()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp создает файл с правами 0600
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
 
-	BandInfo = newBandInfo
+	return os.Rename(tmp.Name(), path)
+}
 
-	defer bandInfoMu.Unlock()
+// Функция загрузки данных из API в проверенный снимок
+func fetchSnapshot() (Snapshot, error) {
+	newBandInfo, err := GetBandInfo(artistAPI)
+	if err != nil {
+		return Snapshot{}, err
+	}
 
 	newRelationInfo, err := GetRelationsInfo(relationAPI)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	newLocationInfo, err := GetLocationsInfo(locationsAPI)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{
+		Bands:     newBandInfo,
+		Relations: newRelationInfo,
+		Locations: newLocationInfo,
+		Time:      time.Now(),
+		Source:    SourceAPI,
+	}
+
+	snapshot, report := ValidateSnapshot(snapshot)
+	setQualityReport(report)
+
+	return snapshot, nil
+}
+
+// Функция обновления кэша из API: данные сохраняются в хранилище и историю,
+// подписчики получают уведомления об изменениях
+func UpdateCache() error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	snapshot, err := fetchSnapshot()
 	if err != nil {
 		log.Println(err.Error())
+		recordRefreshError(err)
 		return err
 	}
 
-	relationInfoMu.Lock()
+	previous := currentSnapshot()
+	if previous != nil {
+		cacheStateMu.Lock()
+		previousSnapshot = previous
+		cacheStateMu.Unlock()
+	}
+
+	applySnapshot(snapshot)
+	persistSnapshot(snapshot)
+	saveHistorySnapshot(snapshot)
+	publishChanges(previous, snapshot)
 
-	RelationInfo = newRelationInfo
+	log.Println("Кэш обновлен")
 
-	defer relationInfoMu.Unlock()
+	return nil
+}
 
-	newLocationInfo, err := GetLocationsInfo(locationsAPI)
+// Функция загрузки данных из API только в память, без хранилища, истории и уведомлений.
+// Используется командами, которые только читают данные.
+func FetchData() error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	snapshot, err := fetchSnapshot()
 	if err != nil {
 		log.Println(err.Error())
 		return err
 	}
 
-	locationInfoMu.Lock()
+	applySnapshot(snapshot)
+	return nil
+}
 
-	LocationInfo = newLocationInfo
+// Функция применения снимка данных к кэшу
+func applySnapshot(s Snapshot) {
+	bandInfoMu.Lock()
+	relationInfoMu.Lock()
+	locationInfoMu.Lock()
 
-	defer locationInfoMu.Unlock()
+	BandInfo = s.Bands
+	RelationInfo = s.Relations
+	LocationInfo = s.Locations
 
 	AddLocationsToBand(BandInfo, LocationInfo, RelationInfo)
+	ParseFirstAlbums(BandInfo)
 	ResponseData = FillData(BandInfo)
 
-	log.Println("Кэш обновлен")
+	locationInfoMu.Unlock()
+	relationInfoMu.Unlock()
+	bandInfoMu.Unlock()
 
-	return nil
+	cacheStateMu.Lock()
+	cacheState.LastRefresh = s.Time
+	cacheState.Source = s.Source
+	cacheStateMu.Unlock()
+}
+
+// Функция получения текущего снимка данных, nil если данных еще нет
+func currentSnapshot() *Snapshot {
+	bandInfoMu.RLock()
+	relationInfoMu.RLock()
+	locationInfoMu.RLock()
+	cacheStateMu.Lock()
+	defer func() {
+		cacheStateMu.Unlock()
+		locationInfoMu.RUnlock()
+		relationInfoMu.RUnlock()
+		bandInfoMu.RUnlock()
+	}()
+
+	if BandInfo == nil {
+		return nil
+	}
+
+	return &Snapshot{
+		Bands:     BandInfo,
+		Relations: RelationInfo,
+		Locations: LocationInfo,
+		Time:      cacheState.LastRefresh,
+		Source:    cacheState.Source,
+	}
 }
 
+// Максимальная длина поискового запроса в символах: поиск перебирает все группы,
+// поэтому слишком длинные запросы отклоняются сразу
+const MaxQueryLength = 200
+
+// Ошибки поиска
+var (
+	ErrEmptyQuery   = errors.New("пустой запрос")
+	ErrNoResults    = errors.New("поиск не дал результатов")
+	ErrQueryTooLong = fmt.Errorf("поисковый запрос длиннее %d символов", MaxQueryLength)
+)
+
 // Функция поиска данных в системе данных
 func SearchRecords(records []Band, query string) (*[]Band, error) {
 	sliceBand := make([]Band, 0)
 
 	if query == "" {
-		return nil, fmt.Errorf("Пустой запрос")
+		return nil, ErrEmptyQuery
+	}
+	if utf8.RuneCountInString(query) > MaxQueryLength {
+		return nil, ErrQueryTooLong
+	}
+
+	dateRange, isDateQuery, err := ParseDateQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if isDateQuery {
+		for _, record := range records {
+			if dateRange.Match(record) {
+				sliceBand = append(sliceBand, record)
+			}
+		}
+		if len(sliceBand) > 0 {
+			return &sliceBand, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrNoResults, query)
 	}
 
 	query = removeWords(query)
@@ -120,7 +260,7 @@ func SearchRecords(records []Band, query string) (*[]Band, error) {
 		return &sliceBand, nil
 	}
 
-	return nil, fmt.Errorf("Поиск c запросом %v не дал результатов", query)
+	return nil, fmt.Errorf("%w: %v", ErrNoResults, query)
 }
 
 // Функция поиска данных об участнике группы
@@ -160,12 +300,20 @@ func removeWords(query string) string {
 
 // Функция для объединения с Locations
 func AddLocationsToBand(band []Band, loc Location, relations Relations) {
+	relationsByID := make(map[int]map[string][]string, len(relations.Index))
+	for _, rel := range relations.Index {
+		relationsByID[rel.ID] = rel.DatesLocations
+	}
+	locationsByID := make(map[int][]string, len(loc.Index))
+	for _, l := range loc.Index {
+		locationsByID[l.ID] = l.Locations
+	}
+
 	for i, b := range band {
-		b.Relations = relations.Index[i].DatesLocations
-		b.Locations = loc.Index[i].Locations
+		b.Relations = relationsByID[b.ID]
+		b.Locations = locationsByID[b.ID]
 		band[i] = b
 	}
-	return
 }
 
 // Функция для получения уникальных локаций из набора данных о группах
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибка разбора даты в запросе по диапазону
+var ErrInvalidDate = errors.New("некорректная дата")
+
+// Запрос по диапазону дат: "first album between 1970 and 1975", "formed before 1980"
+var dateQueryRe = regexp.MustCompile(`(?i)^\s*(first album:?|album:?|formed|created|creation date:?|creation:?)\s+` +
+	`(?:between\s+(\S+)\s+and\s+(\S+)|from\s+(\S+)\s+to\s+(\S+)|(before)\s+(\S+)|(after)\s+(\S+)|in\s+(\S+))\s*$`)
+
+// Диапазон дат для поиска, нулевая граница означает открытый диапазон
+type DateRange struct {
+	Field string
+	From  time.Time
+	To    time.Time
+}
+
+// Функция разбора даты первого альбома вида "dd-mm-yyyy"
+func ParseFirstAlbum(s string) (time.Time, error) {
+	t, err := time.Parse(concertDateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректная дата первого альбома %q", s)
+	}
+	return t, nil
+}
+
+// Функция заполнения дат первых альбомов при загрузке данных
+func ParseFirstAlbums(bands []Band) {
+	for i := range bands {
+		t, err := ParseFirstAlbum(bands[i].FirstAlbum)
+		if err != nil {
+			log.Println(bands[i].Name+":", err)
+			continue
+		}
+		bands[i].FirstAlbumDate = t
+	}
+}
+
+// Функция разбора границы диапазона: год или точная дата.
+// Возвращает начало и конец периода.
+func parseDateBound(s string) (time.Time, time.Time, error) {
+	if year, err := strconv.Atoi(s); err == nil && len(s) == 4 {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1), nil
+	}
+
+	t, err := time.Parse(concertDateLayout, s)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+	}
+	return t, t, nil
+}
+
+// Функция разбора запроса по диапазону дат.
+// Второе значение false, если запрос не относится к датам.
+func ParseDateQuery(query string) (DateRange, bool, error) {
+	m := dateQueryRe.FindStringSubmatch(query)
+	if m == nil {
+		return DateRange{}, false, nil
+	}
+
+	r := DateRange{Field: "creationDate"}
+	if field := strings.ToLower(m[1]); strings.HasPrefix(field, "first album") || strings.HasPrefix(field, "album") {
+		r.Field = "firstAlbum"
+	}
+
+	var err error
+	bounds := func(from, to string) {
+		var fromEnd, toStart time.Time
+		if r.From, fromEnd, err = parseDateBound(from); err != nil {
+			return
+		}
+		if toStart, r.To, err = parseDateBound(to); err != nil {
+			return
+		}
+		if r.To.Before(r.From) {
+			r.From, r.To = toStart, fromEnd
+		}
+	}
+
+	switch {
+	case m[2] != "":
+		bounds(m[2], m[3])
+	case m[4] != "":
+		bounds(m[4], m[5])
+	case m[6] != "":
+		var start time.Time
+		start, _, err = parseDateBound(m[7])
+		r.To = start.AddDate(0, 0, -1)
+	case m[8] != "":
+		var end time.Time
+		_, end, err = parseDateBound(m[9])
+		r.From = end.AddDate(0, 0, 1)
+	default:
+		r.From, r.To, err = parseDateBound(m[10])
+	}
+	if err != nil {
+		return DateRange{}, true, err
+	}
+
+	return r, true, nil
+}
+
+// Функция проверки, попадает ли группа в диапазон дат
+func (r DateRange) Match(b Band) bool {
+	if r.Field == "creationDate" {
+		if b.CreationDate == 0 {
+			return false
+		}
+		return (r.From.IsZero() || b.CreationDate >= r.From.Year()) &&
+			(r.To.IsZero() || b.CreationDate <= r.To.Year())
+	}
+
+	if b.FirstAlbumDate.IsZero() {
+		return false
+	}
+	return (r.From.IsZero() || !b.FirstAlbumDate.Before(r.From)) &&
+		(r.To.IsZero() || !b.FirstAlbumDate.After(r.To))
+}
@@ -1,50 +1,112 @@
 package pkg
 
 import (
+	"encoding/json"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
+	"unicode/utf8"
 )
 
-var (
-	templates *template.Template
-	query     string
-)
+var templates *template.Template
+
+// Данные для страницы группы
+type bandPage struct {
+	Band
+	BandMembers []Member
+	Related     []RelatedBand
+	Map         TourMap
+	Tour        TourStats
+	User        *User
+}
+
+// Данные для главной страницы
+type homePage struct {
+	Band   []Band
+	Search Search
+	Pager  Pager
+	User   *User
+	// Предстоящие концерты групп, на которые подписан пользователь
+	Upcoming []UpcomingConcert
+}
+
+// Данные для страницы результатов поиска
+type searchPage struct {
+	Query string
+	Band  []Band
+	Pager Pager
+}
+
+// Функция получения группы со связями по номеру
+func bandByID(numID int) (Band, bool) {
+	bandInfoMu.RLock()
+	defer bandInfoMu.RUnlock()
+
+	for _, band := range ResponseData.Band {
+		if band.ID == numID {
+			return band, true
+		}
+	}
+
+	return Band{}, false
+}
 
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
-		ErrorHandler(w, http.StatusNotFound)
+		ErrorHandler(w, r, http.StatusNotFound)
 		return
 	}
 
 	if r.Method != http.MethodGet {
-		ErrorHandler(w, http.StatusMethodNotAllowed)
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, err := ParseListOptions(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusBadRequest)
 		return
 	}
 
-	templates, err := template.ParseGlob("./web/templates/*.html")
+	// Под блокировкой только берутся данные: сортировка и вывод страницы идут без нее
+	bandInfoMu.RLock()
+	all, search := ResponseData.Band, ResponseData.Search
+	bandInfoMu.RUnlock()
+
+	bands := PaginateBands(all, opts)
+	page := homePage{Band: bands.Items, Search: search, Pager: newPager(r, opts, bands)}
+
+	if user, ok := CurrentUser(r); ok {
+		page.User = &user
+		page.Upcoming = UpcomingConcerts(all, user.Follows, time.Now())
+	}
+
+	templates, err := parseTemplates(r)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
-	err = templates.ExecuteTemplate(w, "index.html", &ResponseData)
+	err = templates.ExecuteTemplate(w, "index.html", &page)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
 }
 
 func BandHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/band" {
-		ErrorHandler(w, http.StatusNotFound)
+		ErrorHandler(w, r, http.StatusNotFound)
 		return
 	}
 
 	if r.Method != http.MethodGet {
-		ErrorHandler(w, http.StatusMethodNotAllowed)
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -53,80 +115,128 @@ func BandHandler(w http.ResponseWriter, r *http.Request) {
 	numID, err := strconv.Atoi(id)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
 
-	if numID > len(ResponseData.Band) {
-		log.Println(err)
-		ErrorHandler(w, http.StatusNotFound)
+	band, ok := bandByID(numID)
+	if !ok {
+		ErrorHandler(w, r, http.StatusNotFound)
 		return
 	}
 
-	band := ResponseData.Band[numID-1]
+	bandInfoMu.RLock()
+	members := BuildMembers(ResponseData.Band)
+	bandInfoMu.RUnlock()
 
-	band.Relations = RelationInfo.Index[numID-1].DatesLocations
+	page := bandPage{
+		Band:        band,
+		BandMembers: bandMembers(band, members),
+		Related:     relatedBands(band, members),
+		Map:         BuildTourMap(band.Relations),
+		Tour:        ComputeTour(band.Relations),
+	}
+	page.Map.SetRoute(page.Tour.Route)
+	if user, ok := CurrentUser(r); ok {
+		page.User = &user
+	}
 
-	templates, err := template.ParseGlob("./web/templates/*.html")
+	templates, err := parseTemplates(r)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
 
-	err = templates.ExecuteTemplate(w, "band.html", &band)
+	err = templates.ExecuteTemplate(w, "band.html", &page)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
 }
 
 func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/search" {
-		ErrorHandler(w, http.StatusNotFound)
+		ErrorHandler(w, r, http.StatusNotFound)
 		return
 	}
 
 	if r.Method != http.MethodGet {
-		ErrorHandler(w, http.StatusMethodNotAllowed)
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	if utf8.RuneCountInString(query) > MaxQueryLength {
+		log.Println(ErrQueryTooLong, "- запрос с адреса", r.RemoteAddr)
+		renderError(w, r, http.StatusBadRequest, LocalizeError(RequestLocale(r), ErrQueryTooLong))
+		return
+	}
+
+	opts, err := ParseListOptions(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusBadRequest)
 		return
 	}
 
-	query = r.URL.Query().Get("query")
-	
+	bandInfoMu.RLock()
 	band, err := SearchRecords(ResponseData.Band, query)
+	bandInfoMu.RUnlock()
 	if err != nil {
 		log.Println(err)
-		NotFoundHandler(w, http.StatusNotFound)
+		// Ничего не найдено - 404, некорректный запрос, например дата в поиске по датам, - 400
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrNoResults) || errors.Is(err, ErrEmptyQuery) {
+			status = http.StatusNotFound
+		}
+		renderError(w, r, status, LocalizeError(RequestLocale(r), err))
 		return
 	}
-	templates, err := template.ParseGlob("./web/templates/*.html")
+
+	bands := PaginateBands(*band, opts)
+	page := searchPage{Query: query, Band: bands.Items, Pager: newPager(r, opts, bands)}
+
+	templates, err := parseTemplates(r)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
-	err = templates.ExecuteTemplate(w, "search.html", &band)
+	err = templates.ExecuteTemplate(w, "search.html", &page)
 	if err != nil {
 		log.Println(err)
-		ErrorHandler(w, http.StatusInternalServerError)
+		ErrorHandler(w, r, http.StatusInternalServerError)
 		return
 	}
 }
 
-func ErrorHandler(w http.ResponseWriter, statusCode int) {
+// Функция вывода страницы ошибки
+func ErrorHandler(w http.ResponseWriter, r *http.Request, statusCode int) {
+	renderError(w, r, statusCode, Translate(RequestLocale(r), "error.title"))
+}
+
+// Функция вывода страницы ошибки, когда данных о группе или участнике нет
+func NotFoundHandler(w http.ResponseWriter, r *http.Request, statusCode int) {
+	renderError(w, r, statusCode, Translate(RequestLocale(r), "error.no_information"))
+}
+
+// Функция вывода страницы ошибки с сообщением на языке запроса
+func renderError(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
 	w.WriteHeader(statusCode)
 
 	data := struct {
 		StatusMsg  string
 		StatusCode int
+		StatusText string
 	}{
-		"Ooops. Error ",
+		message,
 		statusCode,
+		StatusText(RequestLocale(r), statusCode),
 	}
 
-	templates, err := template.ParseGlob("./web/templates/*.html")
+	templates, err := parseTemplates(r)
 	if err != nil {
 		log.Println(err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -141,25 +251,17 @@ func ErrorHandler(w http.ResponseWriter, statusCode int) {
 	}
 }
 
-func NotFoundHandler(w http.ResponseWriter, statusCode int) {
+// Функция отправки ответа в формате JSON
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(statusCode)
-	data := struct {
-		StatusMsg  string
-		StatusCode int
-	}{
-		"We don't have information about this member or group yet :(",
-		statusCode,
-	}
-	templates, err := template.ParseGlob("./web/templates/*.html")
-	if err != nil {
-		log.Println(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
-	}
-	err = templates.ExecuteTemplate(w, "error.html", &data)
-	if err != nil {
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
 		log.Println(err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
 	}
 }
+
+// Функция отправки ошибки в формате JSON на языке запроса
+func writeJSONError(w http.ResponseWriter, r *http.Request, statusCode int) {
+	writeJSON(w, statusCode, map[string]string{"error": StatusText(RequestLocale(r), statusCode)})
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnknownExportFormat = errors.New("неизвестный формат выгрузки")
+
+// Форматы выгрузки и их типы содержимого
+var ExportFormats = map[string]string{
+	"bands.csv":    "text/csv; charset=utf-8",
+	"members.csv":  "text/csv; charset=utf-8",
+	"concerts.csv": "text/csv; charset=utf-8",
+	"bands.jsonl":  "application/x-ndjson; charset=utf-8",
+}
+
+// Функция записи групп в CSV
+func WriteBandsCSV(w io.Writer, bands []Band) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "image", "creation_date", "first_album", "members", "concerts"})
+	for _, b := range bands {
+		cw.Write([]string{
+			strconv.Itoa(b.ID),
+			b.Name,
+			b.Image,
+			strconv.Itoa(b.CreationDate),
+			b.FirstAlbum,
+			strconv.Itoa(len(b.Members)),
+			strconv.Itoa(concertCount(b)),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Функция записи участников групп в CSV
+func WriteMembersCSV(w io.Writer, bands []Band) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"band_id", "band_name", "member", "member_slug"})
+	for _, b := range bands {
+		for _, m := range b.Members {
+			cw.Write([]string{strconv.Itoa(b.ID), b.Name, m, Slugify(m)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Функция записи концертов в CSV
+func WriteConcertsCSV(w io.Writer, bands []Band) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"band_id", "band_name", "location", "city", "country", "date"})
+	for _, b := range bands {
+		for _, c := range ConcertsFromRelations(b.Relations) {
+			city, country := ParseLocation(c.Location)
+			cw.Write([]string{strconv.Itoa(b.ID), b.Name, c.Location, HumanizeKey(city), HumanizeKey(country), c.Date})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Функция записи групп в формате JSON Lines: одна группа на строку
+func WriteJSONLines(w io.Writer, bands []Band) error {
+	enc := json.NewEncoder(w)
+	for _, b := range bands {
+		if err := enc.Encode(newAPIBand(b, false)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Функция выгрузки данных в одном из форматов ExportFormats
+func Export(w io.Writer, format string, bands []Band) error {
+	switch format {
+	case "bands.csv":
+		return WriteBandsCSV(w, bands)
+	case "members.csv":
+		return WriteMembersCSV(w, bands)
+	case "concerts.csv":
+		return WriteConcertsCSV(w, bands)
+	case "bands.jsonl":
+		return WriteJSONLines(w, bands)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownExportFormat, format)
+}
+
+// Функция получения копии текущего списка групп
+func SnapshotBands() []Band {
+	bandInfoMu.RLock()
+	defer bandInfoMu.RUnlock()
+
+	return append([]Band(nil), ResponseData.Band...)
+}
+
+func ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := strings.TrimPrefix(r.URL.Path, "/export/")
+
+	if format == "band.ics" {
+		numID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			ErrorHandler(w, r, http.StatusBadRequest)
+			return
+		}
+		band, ok := bandByID(numID)
+		if !ok {
+			ErrorHandler(w, r, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="band-%d.ics"`, band.ID))
+		if err := WriteICS(w, band.Name, []Band{band}, time.Now()); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	contentType, ok := ExportFormats[format]
+	if !ok {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+format+`"`)
+	if err := Export(w, format, SnapshotBands()); err != nil {
+		log.Println(err)
+	}
+}
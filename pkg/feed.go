@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxFeedEntries = 50
+	feedTitle      = "Groupie Tracker: new bands and concerts"
+	// Постоянная часть идентификаторов записей (tag URI, RFC 4151)
+	feedTagPrefix = "tag:groupie-tracker,2024:"
+)
+
+// Запись ленты изменений
+type FeedEntry struct {
+	ID      string
+	Title   string
+	Summary string
+	Link    string
+	Updated time.Time
+}
+
+// Последняя построенная лента. Сохраненные снимки не меняются, поэтому лента
+// строится заново, только когда снимки добавлены или удалены.
+var (
+	feedCacheMu  sync.Mutex
+	feedCacheKey string
+	feedCache    []FeedEntry
+)
+
+// Функция построения ленты из изменений между соседними сохраненными снимками
+func BuildFeedEntries() ([]FeedEntry, error) {
+	list, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(list)+1)
+	ids = append(ids, historyDir())
+	for _, info := range list {
+		ids = append(ids, info.ID)
+	}
+	key := strings.Join(ids, ",")
+
+	feedCacheMu.Lock()
+	defer feedCacheMu.Unlock()
+
+	if key == feedCacheKey {
+		return feedCache, nil
+	}
+
+	// Каждый снимок читается один раз и сравнивается с более новым соседом
+	var entries []FeedEntry
+	var newer Snapshot
+	hasNewer, complete := false, true
+	for _, info := range list {
+		s, err := LoadSnapshot(info.ID)
+		if err != nil {
+			log.Println("Ошибка при чтении снимка для ленты:", err)
+			hasNewer, complete = false, false
+			continue
+		}
+		if hasNewer {
+			entries = append(entries, FeedEntriesFromDiff(DiffSnapshots(s, newer))...)
+		}
+		newer, hasNewer = s, true
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Updated.After(entries[j].Updated) })
+	if len(entries) > maxFeedEntries {
+		entries = entries[:maxFeedEntries]
+	}
+
+	// Ленту с непрочитанными снимками не запоминаем: ошибка чтения может быть временной
+	if complete {
+		feedCacheKey, feedCache = key, entries
+	}
+
+	return entries, nil
+}
+
+// Функция получения записей ленты: новые группы и новые концерты
+func FeedEntriesFromDiff(diff SnapshotDiff) []FeedEntry {
+	var entries []FeedEntry
+
+	for _, b := range diff.AddedBands {
+		entries = append(entries, FeedEntry{
+			ID:      feedTagPrefix + "band/" + strconv.Itoa(b.ID),
+			Title:   "New band: " + b.Name,
+			Summary: b.Name + " has been added to the tracker.",
+			Link:    "/band?id=" + strconv.Itoa(b.ID),
+			Updated: diff.To,
+		})
+	}
+
+	for _, c := range diff.Changed {
+		for _, concert := range c.AddedConcerts {
+			uid := strings.TrimSuffix(ConcertUID(c.ID, concert), "@"+icsUIDHost)
+			entries = append(entries, FeedEntry{
+				ID:      feedTagPrefix + "concert/" + uid,
+				Title:   fmt.Sprintf("%s: new concert in %s", c.Name, LocationName(concert.Location)),
+				Summary: fmt.Sprintf("%s announced a concert in %s on %s.", c.Name, LocationName(concert.Location), concert.Date),
+				Link:    "/band?id=" + strconv.Itoa(c.ID),
+				Updated: diff.To,
+			})
+		}
+	}
+
+	return entries
+}
+
+// Функция получения адреса сайта из запроса
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if isHTTPS(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssFeed struct {
+	XMLName       xml.Name  `xml:"rss"`
+	Version       string    `xml:"version,attr"`
+	Title         string    `xml:"channel>title"`
+	Link          string    `xml:"channel>link"`
+	Description   string    `xml:"channel>description"`
+	LastBuildDate string    `xml:"channel>lastBuildDate"`
+	Items         []rssItem `xml:"channel>item"`
+}
+
+// Функция получения времени последнего изменения ленты
+func feedUpdated(entries []FeedEntry) time.Time {
+	if len(entries) > 0 {
+		return entries[0].Updated
+	}
+	return GetCacheState().LastRefresh
+}
+
+// Функция отправки ленты в формате XML
+func writeFeed(w http.ResponseWriter, contentType string, feed interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(xml.Header))
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Println(err)
+	}
+}
+
+func AtomFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/feed.atom" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := BuildFeedEntries()
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	base := baseURL(r)
+	feed := atomFeed{
+		ID:      feedTagPrefix + "feed",
+		Title:   feedTitle,
+		Updated: feedUpdated(entries).UTC().Format(time.RFC3339),
+		Author:  "Groupie Tracker",
+		Links: []atomLink{
+			{Href: base + "/feed.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/", Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, e := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      e.ID,
+			Title:   e.Title,
+			Updated: e.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: base + e.Link, Rel: "alternate"},
+			Summary: e.Summary,
+		})
+	}
+
+	writeFeed(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+func RSSFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/feed.rss" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := BuildFeedEntries()
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	base := baseURL(r)
+	feed := rssFeed{
+		Version:       "2.0",
+		Title:         feedTitle,
+		Link:          base + "/",
+		Description:   "New bands and newly announced concerts detected by Groupie Tracker",
+		LastBuildDate: feedUpdated(entries).UTC().Format(time.RFC1123Z),
+	}
+	for _, e := range entries {
+		feed.Items = append(feed.Items, rssItem{
+			Title:       e.Title,
+			Link:        base + e.Link,
+			Description: e.Summary,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Updated.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	writeFeed(w, "application/rss+xml; charset=utf-8", feed)
+}
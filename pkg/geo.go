@@ -0,0 +1,366 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	geoCacheFile    = "geocache.json"
+	mapWidth        = 600.0
+	mapHeight       = 300.0
+	mapPadding      = 20.0
+	onlineGeoPause  = time.Second
+	geocoderTimeout = 10 * time.Second
+)
+
+//go:embed gazetteer.csv
+var gazetteerData []byte
+
+var (
+	ErrLocationNotFound = errors.New("координаты локации не найдены")
+
+	gazetteer     map[string]GeoPoint
+	gazetteerOnce sync.Once
+
+	geoCache         = make(map[string]GeoPoint)
+	geoCacheMu       sync.RWMutex
+	onlineGeocoder   Geocoder
+	geocodeRunningMu sync.Mutex
+)
+
+// Координаты локации
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	// Приблизительные координаты (центр страны), если точное место неизвестно
+	Approximate bool   `json:"approximate,omitempty"`
+	Source      string `json:"source"`
+}
+
+// Поставщик координат для локаций вида "north_carolina-usa"
+type Geocoder interface {
+	Geocode(ctx context.Context, location string) (GeoPoint, error)
+}
+
+// Функция подключения онлайн-поставщика координат для локаций, которых нет в справочнике
+func SetGeocoder(g Geocoder) {
+	geoCacheMu.Lock()
+	defer geoCacheMu.Unlock()
+
+	onlineGeocoder = g
+}
+
+// Функция загрузки встроенного справочника координат
+func loadGazetteer() map[string]GeoPoint {
+	gazetteerOnce.Do(func() {
+		gazetteer = make(map[string]GeoPoint)
+
+		scanner := bufio.NewScanner(bytes.NewReader(gazetteerData))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "key,") {
+				continue
+			}
+
+			fields := strings.Split(line, ",")
+			if len(fields) != 3 {
+				log.Println("Некорректная строка справочника координат:", line)
+				continue
+			}
+			lat, errLat := strconv.ParseFloat(fields[1], 64)
+			lon, errLon := strconv.ParseFloat(fields[2], 64)
+			if errLat != nil || errLon != nil {
+				log.Println("Некорректные координаты в справочнике:", line)
+				continue
+			}
+
+			gazetteer[fields[0]] = GeoPoint{Lat: lat, Lon: lon, Source: "gazetteer"}
+		}
+	})
+
+	return gazetteer
+}
+
+// Офлайн-поставщик координат на основе встроенного справочника
+type GazetteerGeocoder struct{}
+
+func (GazetteerGeocoder) Geocode(ctx context.Context, location string) (GeoPoint, error) {
+	places := loadGazetteer()
+	location = strings.ToLower(strings.TrimSpace(location))
+
+	if p, ok := places[location]; ok {
+		return p, nil
+	}
+
+	_, country := ParseLocation(location)
+	if p, ok := places[country]; ok {
+		p.Approximate = true
+		return p, nil
+	}
+
+	return GeoPoint{}, ErrLocationNotFound
+}
+
+// Онлайн-поставщик координат с API, совместимым с Nominatim (OpenStreetMap)
+type NominatimGeocoder struct {
+	URL       string
+	UserAgent string
+	Client    *http.Client
+}
+
+func NewNominatimGeocoder(baseURL string) *NominatimGeocoder {
+	return &NominatimGeocoder{
+		URL:       strings.TrimSuffix(baseURL, "/"),
+		UserAgent: "groupie-tracker",
+		Client:    &http.Client{Timeout: geocoderTimeout},
+	}
+}
+
+func (g *NominatimGeocoder) Geocode(ctx context.Context, location string) (GeoPoint, error) {
+	q := url.Values{}
+	q.Set("q", LocationName(location))
+	q.Set("format", "json")
+	q.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.URL+"/search?"+q.Encode(), nil)
+	if err != nil {
+		return GeoPoint{}, err
+	}
+	req.Header.Set("User-Agent", g.UserAgent)
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return GeoPoint{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return GeoPoint{}, fmt.Errorf("геокодер вернул статус %v", resp.Status)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return GeoPoint{}, err
+	}
+	if len(results) == 0 {
+		return GeoPoint{}, ErrLocationNotFound
+	}
+
+	lat, errLat := strconv.ParseFloat(results[0].Lat, 64)
+	lon, errLon := strconv.ParseFloat(results[0].Lon, 64)
+	if errLat != nil || errLon != nil {
+		return GeoPoint{}, fmt.Errorf("некорректные координаты от геокодера: %v, %v", results[0].Lat, results[0].Lon)
+	}
+
+	return GeoPoint{Lat: lat, Lon: lon, Source: "online"}, nil
+}
+
+// Функция загрузки кэша координат из файла
+func LoadGeoCache() error {
+	cache := make(map[string]GeoPoint)
+
+	err := readCacheFile(geoCacheFile, &cache)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	geoCacheMu.Lock()
+	geoCache = cache
+	geoCacheMu.Unlock()
+
+	return nil
+}
+
+// Функция получения координат локации: кэш, затем справочник (точно или по стране)
+func LookupLocation(location string) (GeoPoint, bool) {
+	geoCacheMu.RLock()
+	p, ok := geoCache[location]
+	geoCacheMu.RUnlock()
+	if ok {
+		return p, true
+	}
+
+	p, err := GazetteerGeocoder{}.Geocode(context.Background(), location)
+	return p, err == nil
+}
+
+// Функция фонового поиска координат для локаций, найденных только приблизительно.
+// Запросы к онлайн-поставщику идут не чаще одного раза в секунду.
+func GeocodeMissing(locations []string) {
+	geoCacheMu.RLock()
+	geocoder := onlineGeocoder
+	geoCacheMu.RUnlock()
+	if geocoder == nil {
+		return
+	}
+
+	// Одновременно работает только один фоновый поиск
+	if !geocodeRunningMu.TryLock() {
+		return
+	}
+	defer geocodeRunningMu.Unlock()
+
+	found := 0
+	for _, location := range locations {
+		if p, ok := LookupLocation(location); ok && !p.Approximate {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), geocoderTimeout)
+		p, err := geocoder.Geocode(ctx, location)
+		cancel()
+		time.Sleep(onlineGeoPause)
+
+		if err != nil {
+			log.Println("Не удалось получить координаты локации", location, ":", err)
+			continue
+		}
+
+		geoCacheMu.Lock()
+		geoCache[location] = p
+		geoCacheMu.Unlock()
+		found++
+	}
+
+	if found == 0 {
+		return
+	}
+
+	geoCacheMu.RLock()
+	data, err := json.Marshal(geoCache)
+	geoCacheMu.RUnlock()
+	if err != nil {
+		log.Println("Ошибка при преобразовании кэша координат в JSON:", err)
+		return
+	}
+	if err := SaveCacheToFile(geoCacheFile, data); err != nil {
+		log.Println("Ошибка при сохранении кэша координат в файл:", err)
+		return
+	}
+
+	log.Println("Найдены координаты для локаций:", found)
+}
+
+// Функция фонового поиска координат для всех локаций из кэша
+func GeocodeAllLocations() {
+	bandInfoMu.RLock()
+	locations := append([]string(nil), ResponseData.Search.Locations...)
+	bandInfoMu.RUnlock()
+
+	GeocodeMissing(locations)
+}
+
+func init() {
+	OnRefresh(func(diff SnapshotDiff, current Snapshot) {
+		// Локации берутся из нового снимка, чтобы фоновый поиск не читал общий кэш
+		go GeocodeMissing(uniqueLocations(current.Bands))
+	})
+}
+
+// Точка на карте гастролей
+type MapPoint struct {
+	Location    string
+	Name        string
+	Dates       []string
+	Lat, Lon    float64
+	X, Y        float64
+	Approximate bool
+}
+
+// Карта гастролей группы в виде координат для SVG
+type TourMap struct {
+	Width, Height float64
+	Points        []MapPoint
+	// Локации, для которых координаты не найдены
+	Unknown []string
+	// Маршрут гастролей для элемента polyline
+	Route string
+}
+
+// Функция построения карты гастролей по связям "локация - даты"
+func BuildTourMap(datesLocations map[string][]string) TourMap {
+	m := TourMap{Width: mapWidth, Height: mapHeight}
+
+	for location, dates := range datesLocations {
+		p, ok := LookupLocation(location)
+		if !ok {
+			m.Unknown = append(m.Unknown, LocationName(location))
+			continue
+		}
+		m.Points = append(m.Points, MapPoint{
+			Location:    location,
+			Name:        LocationName(location),
+			Dates:       dates,
+			Lat:         p.Lat,
+			Lon:         p.Lon,
+			Approximate: p.Approximate,
+		})
+	}
+
+	sort.Slice(m.Points, func(i, j int) bool { return m.Points[i].Location < m.Points[j].Location })
+	sort.Strings(m.Unknown)
+
+	m.project()
+
+	return m
+}
+
+// Функция пересчета координат точек в координаты SVG (равнопромежуточная проекция)
+func (m *TourMap) project() {
+	if len(m.Points) == 0 {
+		return
+	}
+
+	minLat, maxLat := m.Points[0].Lat, m.Points[0].Lat
+	minLon, maxLon := m.Points[0].Lon, m.Points[0].Lon
+	for _, p := range m.Points {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+	}
+
+	// Минимальный охват, чтобы одна точка или близкие точки не растягивались на всю карту
+	const minSpan = 10.0
+	if maxLat-minLat < minSpan {
+		mid := (maxLat + minLat) / 2
+		minLat, maxLat = mid-minSpan/2, mid+minSpan/2
+	}
+	if maxLon-minLon < minSpan {
+		mid := (maxLon + minLon) / 2
+		minLon, maxLon = mid-minSpan/2, mid+minSpan/2
+	}
+
+	scale := math.Min((m.Width-2*mapPadding)/(maxLon-minLon), (m.Height-2*mapPadding)/(maxLat-minLat))
+	offsetX := (m.Width - (maxLon-minLon)*scale) / 2
+	offsetY := (m.Height - (maxLat-minLat)*scale) / 2
+
+	for i, p := range m.Points {
+		m.Points[i].X = math.Round((offsetX+(p.Lon-minLon)*scale)*10) / 10
+		m.Points[i].Y = math.Round((offsetY+(maxLat-p.Lat)*scale)*10) / 10
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
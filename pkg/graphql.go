@@ -0,0 +1,1152 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Минимальная реализация GraphQL: только запросы (query), фрагменты,
+// переменные и директивы @include/@skip. Мутаций и интроспекции нет.
+// Сообщения об ошибках на английском, так как возвращаются клиентам API.
+
+const (
+	gqlTokEOF = iota
+	gqlTokPunct
+	gqlTokName
+	gqlTokInt
+	gqlTokFloat
+	gqlTokString
+)
+
+const (
+	gqlSelField = iota
+	gqlSelSpread
+	gqlSelInline
+)
+
+var ErrGraphQLSyntax = errors.New("GraphQL syntax error")
+
+type gqlToken struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// Ссылка на переменную запроса: $name
+type gqlVariable string
+
+type gqlArg struct {
+	Name  string
+	Value interface{}
+}
+
+type gqlDirective struct {
+	Name string
+	Args []gqlArg
+}
+
+// Элемент набора полей: поле, ...Фрагмент или ... on Тип { }
+type gqlSelection struct {
+	Kind       int
+	Alias      string
+	Name       string
+	TypeCond   string
+	Args       []gqlArg
+	Directives []gqlDirective
+	Selections []gqlSelection
+}
+
+// Ключ поля в ответе: псевдоним или имя
+func (s gqlSelection) key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+type gqlVarDef struct {
+	Name       string
+	Type       string
+	Default    interface{}
+	HasDefault bool
+}
+
+type gqlOperation struct {
+	Name       string
+	Vars       []gqlVarDef
+	Selections []gqlSelection
+}
+
+type gqlFragment struct {
+	TypeCond   string
+	Selections []gqlSelection
+}
+
+type gqlDocument struct {
+	Operations []*gqlOperation
+	Fragments  map[string]*gqlFragment
+}
+
+// Ошибка в ответе GraphQL
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	return e.Message
+}
+
+// Объект ответа с сохранением порядка полей, как требует спецификация
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newGQLObject() *gqlObject {
+	return &gqlObject{values: map[string]interface{}{}}
+}
+
+func (o *gqlObject) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Функция разбиения текста запроса на лексемы
+func gqlLex(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	i := 0
+	if strings.HasPrefix(src, "\uFEFF") {
+		i = len("\uFEFF")
+	}
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.IndexByte("!$()=:@[]{}|&", c) >= 0:
+			tokens = append(tokens, gqlToken{gqlTokPunct, string(c), i})
+			i++
+		case c == '.':
+			if !strings.HasPrefix(src[i:], "...") {
+				return nil, fmt.Errorf("%w: unexpected character '.' at position %d", ErrGraphQLSyntax, i)
+			}
+			tokens = append(tokens, gqlToken{gqlTokPunct, "...", i})
+			i += 3
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 'a' && src[i] <= 'z' || src[i] >= 'A' && src[i] <= 'Z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, gqlToken{gqlTokName, src[start:i], start})
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			kind := gqlTokInt
+			if c == '-' {
+				i++
+			}
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				kind = gqlTokFloat
+				i++
+				for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = gqlTokFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+					i++
+				}
+			}
+			if src[start:i] == "-" {
+				return nil, fmt.Errorf("%w: invalid number at position %d", ErrGraphQLSyntax, start)
+			}
+			tokens = append(tokens, gqlToken{kind, src[start:i], start})
+		case c == '"':
+			s, n, err := gqlLexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v at position %d", ErrGraphQLSyntax, err, i)
+			}
+			tokens = append(tokens, gqlToken{gqlTokString, s, i})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrGraphQLSyntax, r, i)
+		}
+	}
+	return append(tokens, gqlToken{gqlTokEOF, "", len(src)}), nil
+}
+
+// Функция чтения строки в кавычках, возвращает значение и длину в исходном тексте
+func gqlLexString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n', '\r':
+			return "", 0, errors.New("unterminated string")
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, errors.New("unterminated string")
+			}
+			switch src[i] {
+			case '"', '\\', '/':
+				b.WriteByte(src[i])
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, errors.New("invalid escape sequence")
+				}
+				code, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, errors.New("invalid escape sequence")
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			default:
+				return "", 0, errors.New("invalid escape sequence")
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+type gqlParser struct {
+	tokens []gqlToken
+	i      int
+}
+
+func (p *gqlParser) peek() gqlToken {
+	return p.tokens[p.i]
+}
+
+func (p *gqlParser) next() gqlToken {
+	t := p.tokens[p.i]
+	if t.kind != gqlTokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *gqlParser) isPunct(v string) bool {
+	t := p.peek()
+	return t.kind == gqlTokPunct && t.value == v
+}
+
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %v at position %d", ErrGraphQLSyntax, fmt.Sprintf(format, args...), p.peek().pos)
+}
+
+func (p *gqlParser) expectPunct(v string) error {
+	if !p.isPunct(v) {
+		return p.errorf("expected %q", v)
+	}
+	p.next()
+	return nil
+}
+
+func (p *gqlParser) expectName() (string, error) {
+	t := p.peek()
+	if t.kind != gqlTokName {
+		return "", p.errorf("expected a name")
+	}
+	p.next()
+	return t.value, nil
+}
+
+// Функция разбора текста запроса GraphQL
+func parseGraphQL(src string) (*gqlDocument, error) {
+	tokens, err := gqlLex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &gqlParser{tokens: tokens}
+	doc := &gqlDocument{Fragments: map[string]*gqlFragment{}}
+
+	for p.peek().kind != gqlTokEOF {
+		t := p.peek()
+		switch {
+		case t.kind == gqlTokPunct && t.value == "{":
+			sel, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &gqlOperation{Selections: sel})
+		case t.kind == gqlTokName && t.value == "query":
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case t.kind == gqlTokName && (t.value == "mutation" || t.value == "subscription"):
+			return nil, fmt.Errorf("%w: %v operations are not supported", ErrGraphQLSyntax, t.value)
+		case t.kind == gqlTokName && t.value == "fragment":
+			p.next()
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if on, err := p.expectName(); err != nil || on != "on" {
+				return nil, p.errorf("expected on")
+			}
+			typeCond, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.parseDirectives(); err != nil {
+				return nil, err
+			}
+			sel, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.Fragments[name]; ok {
+				return nil, fmt.Errorf("%w: fragment %v is defined twice", ErrGraphQLSyntax, name)
+			}
+			doc.Fragments[name] = &gqlFragment{TypeCond: typeCond, Selections: sel}
+		default:
+			return nil, p.errorf("unexpected token %q", t.value)
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("%w: document contains no operations", ErrGraphQLSyntax)
+	}
+
+	return doc, nil
+}
+
+func (p *gqlParser) parseOperation() (*gqlOperation, error) {
+	p.next() // query
+	op := &gqlOperation{}
+
+	if p.peek().kind == gqlTokName {
+		op.Name = p.next().value
+	}
+
+	if p.isPunct("(") {
+		p.next()
+		for !p.isPunct(")") {
+			if err := p.expectPunct("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			typ, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			def := gqlVarDef{Name: name, Type: typ}
+			if p.isPunct("=") {
+				p.next()
+				def.Default, err = p.parseValue(true)
+				if err != nil {
+					return nil, err
+				}
+				def.HasDefault = true
+			}
+			op.Vars = append(op.Vars, def)
+		}
+		p.next()
+	}
+
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	sel, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = sel
+
+	return op, nil
+}
+
+// Функция разбора типа переменной: Int, String!, [Int!]!
+func (p *gqlParser) parseType() (string, error) {
+	var typ string
+	if p.isPunct("[") {
+		p.next()
+		inner, err := p.parseType()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.isPunct("!") {
+		p.next()
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *gqlParser) parseSelectionSet() ([]gqlSelection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	var list []gqlSelection
+	for !p.isPunct("}") {
+		if p.peek().kind == gqlTokEOF {
+			return nil, p.errorf("expected \"}\"")
+		}
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, sel)
+	}
+	p.next()
+
+	if len(list) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+
+	return list, nil
+}
+
+func (p *gqlParser) parseSelection() (gqlSelection, error) {
+	var (
+		sel gqlSelection
+		err error
+	)
+
+	if p.isPunct("...") {
+		p.next()
+		if t := p.peek(); t.kind == gqlTokName && t.value != "on" {
+			sel.Kind = gqlSelSpread
+			sel.Name = p.next().value
+			sel.Directives, err = p.parseDirectives()
+			return sel, err
+		}
+		sel.Kind = gqlSelInline
+		if p.peek().kind == gqlTokName {
+			p.next() // on
+			if sel.TypeCond, err = p.expectName(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.Directives, err = p.parseDirectives(); err != nil {
+			return sel, err
+		}
+		sel.Selections, err = p.parseSelectionSet()
+		return sel, err
+	}
+
+	sel.Kind = gqlSelField
+	if sel.Name, err = p.expectName(); err != nil {
+		return sel, err
+	}
+	if p.isPunct(":") {
+		p.next()
+		sel.Alias = sel.Name
+		if sel.Name, err = p.expectName(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.Args, err = p.parseArgs(); err != nil {
+		return sel, err
+	}
+	if sel.Directives, err = p.parseDirectives(); err != nil {
+		return sel, err
+	}
+	if p.isPunct("{") {
+		sel.Selections, err = p.parseSelectionSet()
+	}
+	return sel, err
+}
+
+func (p *gqlParser) parseArgs() ([]gqlArg, error) {
+	if !p.isPunct("(") {
+		return nil, nil
+	}
+	p.next()
+
+	var args []gqlArg
+	for !p.isPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, gqlArg{Name: name, Value: value})
+	}
+	p.next()
+
+	return args, nil
+}
+
+func (p *gqlParser) parseDirectives() ([]gqlDirective, error) {
+	var list []gqlDirective
+	for p.isPunct("@") {
+		p.next()
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, gqlDirective{Name: name, Args: args})
+	}
+	return list, nil
+}
+
+// Функция разбора значения аргумента. В значениях по умолчанию переменные запрещены.
+func (p *gqlParser) parseValue(constant bool) (interface{}, error) {
+	t := p.peek()
+	switch t.kind {
+	case gqlTokPunct:
+		switch t.value {
+		case "$":
+			if constant {
+				return nil, p.errorf("variable in a constant value")
+			}
+			p.next()
+			name, err := p.expectName()
+			return gqlVariable(name), err
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.isPunct("]") {
+				if p.peek().kind == gqlTokEOF {
+					return nil, p.errorf("expected \"]\"")
+				}
+				v, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			p.next()
+			obj := map[string]interface{}{}
+			for !p.isPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.parseValue(constant); err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+			return obj, nil
+		}
+	case gqlTokInt:
+		p.next()
+		n, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, p.errorf("number %v is too large", t.value)
+		}
+		return n, nil
+	case gqlTokFloat:
+		p.next()
+		return strconv.ParseFloat(t.value, 64)
+	case gqlTokString:
+		p.next()
+		return t.value, nil
+	case gqlTokName:
+		p.next()
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// Значения перечислений передаются как строки
+		return t.value, nil
+	}
+	return nil, p.errorf("expected a value")
+}
+
+// Описание аргумента поля схемы
+type gqlArgDef struct {
+	Name     string
+	Type     string
+	Required bool
+	Default  interface{}
+}
+
+// Обработчик поля: получает родительский объект и аргументы
+type gqlResolver func(req *gqlRequest, src interface{}, args map[string]interface{}) (interface{}, error)
+
+// Описание поля схемы. List - поле возвращает список значений типа Type.
+type gqlFieldDef struct {
+	Type string
+	List bool
+	// Ожидаемый размер списка для оценки сложности, если нет аргумента first
+	ListSize    int
+	Args        []gqlArgDef
+	Description string
+	Resolve     gqlResolver
+}
+
+type gqlObjectType struct {
+	Name   string
+	Fields map[string]*gqlFieldDef
+}
+
+type gqlSchema struct {
+	Query string
+	Types map[string]*gqlObjectType
+	// Размер списка для оценки сложности, если у поля не задан ListSize
+	DefaultListSize int
+}
+
+var gqlScalars = map[string]bool{"Int": true, "Float": true, "String": true, "Boolean": true}
+
+// Ограничения на запрос
+type gqlLimits struct {
+	MaxDepth      int
+	MaxComplexity int
+	// Сколько полей можно получить при раскрытии фрагментов: один фрагмент
+	// можно подставить несколько раз на каждом уровне, и короткий запрос
+	// раскрывается в экспоненциальное число полей
+	MaxSelections int
+}
+
+// Состояние выполнения одного запроса
+type gqlRequest struct {
+	schema *gqlSchema
+	doc    *gqlDocument
+	vars   map[string]interface{}
+	errors []GraphQLError
+	// Данные, общие для всех обработчиков полей
+	data *gqlData
+	// Число раскрытых полей и его предел при проверке запроса; 0 - без предела
+	expanded      int
+	maxSelections int
+}
+
+// Функция проверки директив @include и @skip
+func (r *gqlRequest) included(directives []gqlDirective) (bool, error) {
+	for _, d := range directives {
+		if d.Name != "include" && d.Name != "skip" {
+			return false, fmt.Errorf("unknown directive @%v", d.Name)
+		}
+		if len(d.Args) != 1 || d.Args[0].Name != "if" {
+			return false, fmt.Errorf("directive @%v requires an if argument", d.Name)
+		}
+		v, err := r.resolveValue(d.Args[0].Value)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := v.(bool)
+		if !ok {
+			return false, fmt.Errorf("argument if of directive @%v must be Boolean", d.Name)
+		}
+		if d.Name == "include" && !cond || d.Name == "skip" && cond {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Функция подстановки переменных в значение
+func (r *gqlRequest) resolveValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case gqlVariable:
+		value, ok := r.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%v is not defined", v)
+		}
+		return value, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if list[i], err = r.resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			var err error
+			if obj[k], err = r.resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+// Функция учета раскрытых полей: при проверке запроса их число ограничено,
+// чтобы запрос не успел раскрыть фрагменты до проверки глубины и сложности
+func (r *gqlRequest) expand(n int) error {
+	r.expanded += n
+	if r.maxSelections > 0 && r.expanded > r.maxSelections {
+		return fmt.Errorf("query expands to more than %d selections", r.maxSelections)
+	}
+	return nil
+}
+
+// Функция раскрытия фрагментов и директив в плоский список полей
+func (r *gqlRequest) collectFields(selections []gqlSelection, typeName string, visited map[string]bool) ([]gqlSelection, error) {
+	var fields []gqlSelection
+	index := map[string]int{}
+
+	add := func(list []gqlSelection) error {
+		for _, f := range list {
+			if i, ok := index[f.key()]; ok {
+				if fields[i].Name != f.Name {
+					continue
+				}
+				// Объединенный набор полей тоже растет с каждой подстановкой фрагмента
+				if err := r.expand(len(f.Selections)); err != nil {
+					return err
+				}
+				fields[i].Selections = append(append([]gqlSelection(nil), fields[i].Selections...), f.Selections...)
+				continue
+			}
+			index[f.key()] = len(fields)
+			fields = append(fields, f)
+		}
+		return nil
+	}
+
+	for _, sel := range selections {
+		if err := r.expand(1); err != nil {
+			return nil, err
+		}
+
+		ok, err := r.included(sel.Directives)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		switch sel.Kind {
+		case gqlSelField:
+			if err := add([]gqlSelection{sel}); err != nil {
+				return nil, err
+			}
+		case gqlSelSpread:
+			frag, ok := r.doc.Fragments[sel.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %v", sel.Name)
+			}
+			if visited[sel.Name] {
+				return nil, fmt.Errorf("fragment %v spreads itself", sel.Name)
+			}
+			if frag.TypeCond != typeName {
+				continue
+			}
+			visited[sel.Name] = true
+			list, err := r.collectFields(frag.Selections, typeName, visited)
+			delete(visited, sel.Name)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(list); err != nil {
+				return nil, err
+			}
+		case gqlSelInline:
+			if sel.TypeCond != "" && sel.TypeCond != typeName {
+				continue
+			}
+			list, err := r.collectFields(sel.Selections, typeName, visited)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(list); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// Функция приведения аргументов поля к типам схемы
+func (r *gqlRequest) coerceArgs(field gqlSelection, def *gqlFieldDef) (map[string]interface{}, error) {
+	given := map[string]interface{}{}
+	for _, a := range field.Args {
+		v, err := r.resolveValue(a.Value)
+		if err != nil {
+			return nil, err
+		}
+		given[a.Name] = v
+	}
+
+	args := map[string]interface{}{}
+	for _, ad := range def.Args {
+		v, ok := given[ad.Name]
+		delete(given, ad.Name)
+		if !ok || v == nil {
+			if ad.Required {
+				return nil, fmt.Errorf("field %v: missing required argument %v", field.Name, ad.Name)
+			}
+			if ad.Default != nil {
+				args[ad.Name] = ad.Default
+			}
+			continue
+		}
+
+		coerced, err := gqlCoerce(v, ad.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %v, argument %v: %v", field.Name, ad.Name, err)
+		}
+		args[ad.Name] = coerced
+	}
+
+	for name := range given {
+		return nil, fmt.Errorf("field %v: unknown argument %v", field.Name, name)
+	}
+
+	return args, nil
+}
+
+// Функция приведения значения к скалярному типу
+func gqlCoerce(v interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "Int":
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case float64:
+			// Числа из JSON с переменными приходят как float64
+			if n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+				return int(n), nil
+			}
+		}
+	case "Float":
+		switch n := v.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case "String":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a value of type %v", typ)
+}
+
+// Функция проверки запроса по схеме и расчета его сложности
+func (r *gqlRequest) validate(selections []gqlSelection, typeName string, depth int, limits gqlLimits) (int, error) {
+	if depth > limits.MaxDepth {
+		return 0, fmt.Errorf("query is deeper than the maximum depth %d", limits.MaxDepth)
+	}
+
+	typ := r.schema.Types[typeName]
+	fields, err := r.collectFields(selections, typeName, map[string]bool{})
+	if err != nil {
+		return 0, err
+	}
+
+	complexity := 0
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			complexity++
+			continue
+		}
+
+		def, ok := typ.Fields[f.Name]
+		if !ok {
+			return 0, fmt.Errorf("type %v has no field %v", typeName, f.Name)
+		}
+		args, err := r.coerceArgs(f, def)
+		if err != nil {
+			return 0, err
+		}
+
+		cost := 1
+		if gqlScalars[def.Type] {
+			if len(f.Selections) > 0 {
+				return 0, fmt.Errorf("field %v of type %v cannot have a selection set", f.Name, def.Type)
+			}
+		} else {
+			if len(f.Selections) == 0 {
+				return 0, fmt.Errorf("field %v of type %v must have a selection set", f.Name, def.Type)
+			}
+			child, err := r.validate(f.Selections, def.Type, depth+1, limits)
+			if err != nil {
+				return 0, err
+			}
+			cost += child
+		}
+
+		if def.List {
+			size := def.ListSize
+			if size == 0 {
+				size = r.schema.DefaultListSize
+			}
+			if n, ok := args["first"].(int); ok && n >= 0 {
+				size = n
+			}
+			cost *= size
+		}
+
+		complexity += cost
+		if complexity > limits.MaxComplexity {
+			return 0, fmt.Errorf("query complexity exceeds the limit %d", limits.MaxComplexity)
+		}
+	}
+
+	return complexity, nil
+}
+
+// Функция выполнения набора полей над объектом
+func (r *gqlRequest) executeFields(selections []gqlSelection, typeName string, src interface{}, path []interface{}) *gqlObject {
+	obj := newGQLObject()
+	typ := r.schema.Types[typeName]
+
+	// Ошибки уже проверены в validate
+	fields, _ := r.collectFields(selections, typeName, map[string]bool{})
+	for _, f := range fields {
+		fieldPath := append(append([]interface{}(nil), path...), f.key())
+
+		if f.Name == "__typename" {
+			obj.set(f.key(), typeName)
+			continue
+		}
+
+		def := typ.Fields[f.Name]
+		args, _ := r.coerceArgs(f, def)
+
+		value, err := def.Resolve(r, src, args)
+		if err != nil {
+			r.errors = append(r.errors, GraphQLError{Message: err.Error(), Path: fieldPath})
+			obj.set(f.key(), nil)
+			continue
+		}
+
+		obj.set(f.key(), r.completeValue(f, def, value, fieldPath))
+	}
+
+	return obj
+}
+
+// Функция преобразования результата обработчика поля в значение ответа
+func (r *gqlRequest) completeValue(f gqlSelection, def *gqlFieldDef, value interface{}, path []interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	if def.List {
+		items, _ := value.([]interface{})
+		list := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			itemPath := append(append([]interface{}(nil), path...), i)
+			list = append(list, r.completeItem(f, def, item, itemPath))
+		}
+		return list
+	}
+
+	return r.completeItem(f, def, value, path)
+}
+
+func (r *gqlRequest) completeItem(f gqlSelection, def *gqlFieldDef, value interface{}, path []interface{}) interface{} {
+	if value == nil || gqlScalars[def.Type] {
+		return value
+	}
+	return r.executeFields(f.Selections, def.Type, value, path)
+}
+
+// Функция выполнения запроса: разбор, проверка ограничений и вычисление ответа
+func executeGraphQL(schema *gqlSchema, data *gqlData, query, operationName string, variables map[string]interface{}, limits gqlLimits) (interface{}, []GraphQLError) {
+	doc, err := parseGraphQL(query)
+	if err != nil {
+		return nil, []GraphQLError{{Message: err.Error()}}
+	}
+
+	var op *gqlOperation
+	for _, o := range doc.Operations {
+		if operationName == "" && len(doc.Operations) == 1 || o.Name == operationName && operationName != "" {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		if operationName == "" {
+			return nil, []GraphQLError{{Message: "document contains several operations, operationName is required"}}
+		}
+		return nil, []GraphQLError{{Message: fmt.Sprintf("unknown operation %v", operationName)}}
+	}
+
+	req := &gqlRequest{schema: schema, doc: doc, vars: map[string]interface{}{}, data: data}
+	for _, v := range op.Vars {
+		value, ok := variables[v.Name]
+		if !ok && v.HasDefault {
+			value, ok = v.Default, true
+		}
+		if !ok || value == nil {
+			if strings.HasSuffix(v.Type, "!") {
+				return nil, []GraphQLError{{Message: fmt.Sprintf("missing required variable $%v", v.Name)}}
+			}
+			continue
+		}
+		base := strings.TrimSuffix(v.Type, "!")
+		if gqlScalars[base] {
+			if value, err = gqlCoerce(value, base); err != nil {
+				return nil, []GraphQLError{{Message: fmt.Sprintf("variable $%v: %v", v.Name, err)}}
+			}
+		}
+		req.vars[v.Name] = value
+	}
+
+	req.maxSelections = limits.MaxSelections
+	if _, err := req.validate(op.Selections, schema.Query, 1, limits); err != nil {
+		return nil, []GraphQLError{{Message: err.Error()}}
+	}
+	// Проверенный запрос раскрывается для каждого объекта заново, его размер уже ограничен сложностью
+	req.maxSelections = 0
+
+	result := req.executeFields(op.Selections, schema.Query, nil, nil)
+	return result, req.errors
+}
+
+// Функция получения описания схемы на языке SDL
+func (s *gqlSchema) SDL() string {
+	names := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		// Тип Query выводится первым
+		if (names[i] == s.Query) != (names[j] == s.Query) {
+			return names[i] == s.Query
+		}
+		return names[i] < names[j]
+	})
+
+	var b strings.Builder
+	for _, name := range names {
+		typ := s.Types[name]
+		fmt.Fprintf(&b, "type %v {\n", name)
+
+		fieldNames := make([]string, 0, len(typ.Fields))
+		for f := range typ.Fields {
+			fieldNames = append(fieldNames, f)
+		}
+		sort.Strings(fieldNames)
+
+		for _, fname := range fieldNames {
+			def := typ.Fields[fname]
+			if def.Description != "" {
+				fmt.Fprintf(&b, "  # %v\n", def.Description)
+			}
+			b.WriteString("  " + fname)
+			if len(def.Args) > 0 {
+				var args []string
+				for _, a := range def.Args {
+					arg := a.Name + ": " + a.Type
+					if a.Required {
+						arg += "!"
+					}
+					if a.Default != nil {
+						arg += fmt.Sprintf(" = %v", a.Default)
+					}
+					args = append(args, arg)
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			typeName := def.Type
+			if def.List {
+				typeName = "[" + typeName + "!]!"
+			}
+			b.WriteString(": " + typeName + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	return strings.TrimSpace(b.String()) + "\n"
+}
@@ -0,0 +1,484 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistoryDir   = "snapshots"
+	historyIndexFile    = "index.json"
+	defaultHistoryLimit = 10
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("снимок данных не найден")
+
+	// Оглавление снимков читается и перезаписывается только под этой блокировкой
+	historyMu sync.Mutex
+)
+
+// Краткие сведения о сохраненном снимке
+type SnapshotInfo struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Bands  int       `json:"bands"`
+}
+
+// Концерт группы: место и дата
+type Concert struct {
+	Location string `json:"location"`
+	Date     string `json:"date"`
+}
+
+// Ссылка на группу в описании изменений
+type BandRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Изменения данных одной группы между снимками
+type BandChange struct {
+	BandRef
+	AddedMembers      []string  `json:"addedMembers,omitempty"`
+	RemovedMembers    []string  `json:"removedMembers,omitempty"`
+	AddedConcerts     []Concert `json:"addedConcerts,omitempty"`
+	CancelledConcerts []Concert `json:"cancelledConcerts,omitempty"`
+}
+
+// Разница между двумя снимками данных
+type SnapshotDiff struct {
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	AddedBands   []BandRef    `json:"addedBands"`
+	RemovedBands []BandRef    `json:"removedBands"`
+	Changed      []BandChange `json:"changed"`
+}
+
+// Функция проверки наличия изменений
+func (d SnapshotDiff) Empty() bool {
+	return len(d.AddedBands) == 0 && len(d.RemovedBands) == 0 && len(d.Changed) == 0
+}
+
+// Функция сравнения двух снимков данных
+func DiffSnapshots(from, to Snapshot) SnapshotDiff {
+	diff := SnapshotDiff{
+		From:         from.Time,
+		To:           to.Time,
+		AddedBands:   []BandRef{},
+		RemovedBands: []BandRef{},
+		Changed:      []BandChange{},
+	}
+
+	oldBands := bandsByID(from.Bands)
+	newBands := bandsByID(to.Bands)
+	oldConcerts := concertsByID(from.Relations)
+	newConcerts := concertsByID(to.Relations)
+
+	for _, b := range to.Bands {
+		old, ok := oldBands[b.ID]
+		if !ok {
+			diff.AddedBands = append(diff.AddedBands, BandRef{ID: b.ID, Name: b.Name})
+			continue
+		}
+
+		change := BandChange{
+			BandRef:           BandRef{ID: b.ID, Name: b.Name},
+			AddedMembers:      missingStrings(b.Members, old.Members),
+			RemovedMembers:    missingStrings(old.Members, b.Members),
+			AddedConcerts:     missingConcerts(newConcerts[b.ID], oldConcerts[b.ID]),
+			CancelledConcerts: missingConcerts(oldConcerts[b.ID], newConcerts[b.ID]),
+		}
+		if len(change.AddedMembers) > 0 || len(change.RemovedMembers) > 0 ||
+			len(change.AddedConcerts) > 0 || len(change.CancelledConcerts) > 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	for _, b := range from.Bands {
+		if _, ok := newBands[b.ID]; !ok {
+			diff.RemovedBands = append(diff.RemovedBands, BandRef{ID: b.ID, Name: b.Name})
+		}
+	}
+
+	return diff
+}
+
+func bandsByID(bands []Band) map[int]Band {
+	m := make(map[int]Band, len(bands))
+	for _, b := range bands {
+		m[b.ID] = b
+	}
+	return m
+}
+
+// Функция получения списка концертов каждой группы из связей
+func concertsByID(relations Relations) map[int][]Concert {
+	m := make(map[int][]Concert, len(relations.Index))
+	for _, rel := range relations.Index {
+		m[rel.ID] = ConcertsFromRelations(rel.DatesLocations)
+	}
+	return m
+}
+
+// Функция преобразования связей "локация - даты" в отсортированный список концертов
+func ConcertsFromRelations(datesLocations map[string][]string) []Concert {
+	var concerts []Concert
+	for location, dates := range datesLocations {
+		for _, date := range dates {
+			concerts = append(concerts, Concert{Location: location, Date: date})
+		}
+	}
+	sort.Slice(concerts, func(i, j int) bool {
+		if concerts[i].Location != concerts[j].Location {
+			return concerts[i].Location < concerts[j].Location
+		}
+		return concerts[i].Date < concerts[j].Date
+	})
+	return concerts
+}
+
+// Функция поиска строк из a, которых нет в b
+func missingStrings(a, b []string) []string {
+	var missing []string
+	for _, s := range a {
+		if !repeatString(b, s) {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// Функция поиска концертов из a, которых нет в b
+func missingConcerts(a, b []Concert) []Concert {
+	set := make(map[Concert]bool, len(b))
+	for _, c := range b {
+		set[c] = true
+	}
+	var missing []Concert
+	for _, c := range a {
+		if !set[c] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// Функция получения каталога истории снимков из переменной окружения HISTORY_DIR
+func historyDir() string {
+	if dir := os.Getenv("HISTORY_DIR"); dir != "" {
+		return dir
+	}
+	return defaultHistoryDir
+}
+
+// Функция получения количества хранимых снимков из переменной окружения SNAPSHOT_LIMIT
+func historyLimit() int {
+	n, err := strconv.Atoi(os.Getenv("SNAPSHOT_LIMIT"))
+	if err != nil || n < 1 {
+		return defaultHistoryLimit
+	}
+	return n
+}
+
+// Функция сохранения снимка на диск, если данные изменились с последнего сохранения
+func saveHistorySnapshot(s Snapshot) {
+	list, err := ListSnapshots()
+	if err != nil {
+		log.Println("Ошибка при чтении истории снимков:", err)
+		return
+	}
+
+	if len(list) > 0 {
+		last, err := LoadSnapshot(list[0].ID)
+		if err == nil && DiffSnapshots(last, s).Empty() {
+			return
+		}
+	}
+
+	if err := os.MkdirAll(historyDir(), 0o755); err != nil {
+		log.Println("Ошибка при создании каталога истории снимков:", err)
+		return
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Println("Ошибка при преобразовании снимка в JSON:", err)
+		return
+	}
+
+	id := strconv.FormatInt(s.Time.UnixNano(), 10)
+	if err := replaceFile(snapshotPath(id), data, 0o644); err != nil {
+		log.Println("Ошибка при сохранении снимка в файл:", err)
+		return
+	}
+	if err := indexSnapshot(SnapshotInfo{ID: id, Time: s.Time, Source: s.Source, Bands: len(s.Bands)}); err != nil {
+		log.Println("Ошибка при обновлении оглавления снимков:", err)
+	}
+
+	list, err = ListSnapshots()
+	if err != nil {
+		return
+	}
+	if len(list) <= historyLimit() {
+		return
+	}
+	for _, old := range list[historyLimit():] {
+		if err := os.Remove(snapshotPath(old.ID)); err != nil {
+			log.Println("Ошибка при удалении старого снимка:", err)
+		}
+	}
+}
+
+func snapshotPath(id string) string {
+	return filepath.Join(historyDir(), "snapshot-"+id+".json")
+}
+
+// Функция чтения оглавления снимков. Поврежденное оглавление не мешает работе:
+// оно собирается заново из файлов снимков.
+func readHistoryIndex() map[string]SnapshotInfo {
+	var list []SnapshotInfo
+	err := readCacheFile(filepath.Join(historyDir(), historyIndexFile), &list)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Println("Ошибка при чтении оглавления снимков:", err)
+	}
+
+	index := make(map[string]SnapshotInfo, len(list))
+	for _, info := range list {
+		index[info.ID] = info
+	}
+	return index
+}
+
+func writeHistoryIndex(list []SnapshotInfo) error {
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(historyDir(), historyIndexFile), data, 0o644)
+}
+
+// Функция добавления сведений о новом снимке в оглавление
+func indexSnapshot(info SnapshotInfo) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	index := readHistoryIndex()
+	index[info.ID] = info
+
+	list := make([]SnapshotInfo, 0, len(index))
+	for _, i := range index {
+		list = append(list, i)
+	}
+	return writeHistoryIndex(list)
+}
+
+// Функция получения списка сохраненных снимков, от новых к старым.
+// Сведения о снимках берутся из оглавления; файл снимка читается,
+// только если его нет в оглавлении, например после обновления программы.
+func ListSnapshots() ([]SnapshotInfo, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	entries, err := os.ReadDir(historyDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return []SnapshotInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := readHistoryIndex()
+	list := []SnapshotInfo{}
+	changed := false
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "snapshot-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, "snapshot-"), ".json")
+		info, ok := index[id]
+		if !ok {
+			s, err := LoadSnapshot(id)
+			if err != nil {
+				log.Println("Ошибка при чтении снимка", name, err)
+				continue
+			}
+			info = SnapshotInfo{ID: id, Time: s.Time, Source: s.Source, Bands: len(s.Bands)}
+			changed = true
+		}
+		list = append(list, info)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.After(list[j].Time)
+	})
+
+	// В оглавлении остаются только существующие снимки
+	if changed || len(list) != len(index) {
+		if err := writeHistoryIndex(list); err != nil {
+			log.Println("Ошибка при обновлении оглавления снимков:", err)
+		}
+	}
+
+	return list, nil
+}
+
+// Функция загрузки снимка по идентификатору
+func LoadSnapshot(id string) (Snapshot, error) {
+	var s Snapshot
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return s, ErrSnapshotNotFound
+	}
+
+	if err := readCacheFile(snapshotPath(id), &s); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, ErrSnapshotNotFound
+		}
+		return s, fmt.Errorf("снимок %v: %w", id, err)
+	}
+
+	return s, nil
+}
+
+// Функция сравнения двух сохраненных снимков.
+// Без идентификаторов сравниваются два последних снимка.
+func DiffStoredSnapshots(fromID, toID string) (SnapshotDiff, error) {
+	if fromID == "" || toID == "" {
+		list, err := ListSnapshots()
+		if err != nil {
+			return SnapshotDiff{}, err
+		}
+		if len(list) < 2 {
+			return SnapshotDiff{}, ErrSnapshotNotFound
+		}
+		if toID == "" {
+			toID = list[0].ID
+		}
+		if fromID == "" {
+			fromID = list[1].ID
+		}
+	}
+
+	from, err := LoadSnapshot(fromID)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	to, err := LoadSnapshot(toID)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	return DiffSnapshots(from, to), nil
+}
+
+// Данные для страницы истории снимков
+type historyPage struct {
+	Snapshots []SnapshotInfo
+	From, To  string
+	Diff      *SnapshotDiff
+	Error     string
+}
+
+func HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/history" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, err := ListSnapshots()
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	page := historyPage{
+		Snapshots: list,
+		From:      r.URL.Query().Get("from"),
+		To:        r.URL.Query().Get("to"),
+	}
+
+	diff, err := DiffStoredSnapshots(page.From, page.To)
+	if err != nil {
+		page.Error = LocalizeError(RequestLocale(r), err)
+	} else {
+		page.Diff = &diff
+	}
+
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+	err = templates.ExecuteTemplate(w, "history.html", &page)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
+
+func APIHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/history" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, err := ListSnapshots()
+	if err != nil {
+		log.Println(err)
+		writeJSONError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func APIHistoryDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/history/diff" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	diff, err := DiffStoredSnapshots(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if errors.Is(err, ErrSnapshotNotFound) {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		writeJSONError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
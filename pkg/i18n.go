@@ -0,0 +1,267 @@
+package pkg
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Язык по умолчанию: на нем показываются страницы, если клиент не выбрал другой
+const DefaultLocale = "en"
+
+// Имя cookie и параметра запроса для выбора языка
+const localeParam = "lang"
+
+//go:embed locales/*.json locales/cldr/*.json
+var localeFiles embed.FS
+
+// Поддерживаемые языки в порядке вывода в переключателе
+var supportedLocales = []string{"en", "ru"}
+
+// Каталоги сообщений: язык -> ключ -> шаблон сообщения
+var catalogs = loadCatalogs()
+
+// Язык для переключателя на странице
+type Language struct {
+	Code string
+	Name string
+}
+
+// Функция загрузки каталогов сообщений из встроенных файлов
+func loadCatalogs() map[string]map[string]string {
+	result := make(map[string]map[string]string, len(supportedLocales))
+	for _, locale := range supportedLocales {
+		data, err := localeFiles.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("каталог сообщений %s не найден: %v", locale, err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("каталог сообщений %s поврежден: %v", locale, err))
+		}
+		result[locale] = messages
+	}
+	return result
+}
+
+// Функция получения списка поддерживаемых языков
+func SupportedLocales() []string {
+	return append([]string(nil), supportedLocales...)
+}
+
+// Функция получения ключей каталога сообщений языка в алфавитном порядке
+func CatalogKeys(locale string) []string {
+	keys := make([]string, 0, len(catalogs[locale]))
+	for key := range catalogs[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Функция перевода сообщения. Если ключа нет в каталоге языка, берется английский
+// вариант, а если нет и его - сам ключ. Аргументы подставляются как в fmt.Sprintf.
+func Translate(locale, key string, args ...interface{}) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		log.Println("Нет перевода для ключа", key)
+		message = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Функция приведения тега языка (en-US, RU) к поддерживаемому языку
+func matchLocale(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(tag, "-")
+	for _, locale := range supportedLocales {
+		if primary == locale {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Функция выбора языка по заголовку Accept-Language с учетом весов q
+func NegotiateLocale(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if locale, ok := matchLocale(c.tag); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Функция определения языка запроса: параметр ?lang=, затем cookie, затем Accept-Language
+func RequestLocale(r *http.Request) string {
+	if locale, ok := matchLocale(r.URL.Query().Get(localeParam)); ok {
+		return locale
+	}
+	if cookie, err := r.Cookie(localeParam); err == nil {
+		if locale, ok := matchLocale(cookie.Value); ok {
+			return locale
+		}
+	}
+	return NegotiateLocale(r.Header.Get("Accept-Language"))
+}
+
+// Функция-обертка, которая запоминает язык, выбранный параметром ?lang=, в cookie
+// и сообщает клиенту язык ответа
+func WithLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if locale, ok := matchLocale(r.URL.Query().Get(localeParam)); ok {
+			http.SetCookie(w, &http.Cookie{
+				Name:     localeParam,
+				Value:    locale,
+				Path:     "/",
+				MaxAge:   365 * 24 * 60 * 60,
+				HttpOnly: true,
+				Secure:   isHTTPS(r),
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		w.Header().Set("Content-Language", RequestLocale(r))
+		w.Header().Add("Vary", "Accept-Language, Cookie")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Функция получения текста статуса HTTP на языке запроса
+func StatusText(locale string, statusCode int) string {
+	key := "status." + strconv.Itoa(statusCode)
+	if _, ok := catalogs[DefaultLocale][key]; ok {
+		return Translate(locale, key)
+	}
+	return http.StatusText(statusCode)
+}
+
+// Ошибки, для которых в каталоге есть перевод
+var errorMessages = []struct {
+	err  error
+	key  string
+	args []interface{}
+}{
+	{ErrEmptyQuery, "error.empty_query", nil},
+	{ErrNoResults, "error.no_results", nil},
+	{ErrQueryTooLong, "error.query_too_long", []interface{}{MaxQueryLength}},
+	{ErrInvalidDate, "error.invalid_date", nil},
+	{ErrInvalidListOptions, "error.list_options", nil},
+	{ErrNoPreviousSnapshot, "error.no_previous_snapshot", nil},
+	{ErrSnapshotNotFound, "error.snapshot_not_found", nil},
+	{ErrWebhookNotFound, "error.webhook_not_found", nil},
+	{ErrInvalidWebhookURL, "error.webhook_url", nil},
+}
+
+// Функция перевода ошибки для пользователя. Подробности, которые обертка добавила
+// после сообщения исходной ошибки (например, запрос поиска), сохраняются.
+// Ошибки без перевода возвращаются как есть.
+func LocalizeError(locale string, err error) string {
+	for _, m := range errorMessages {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+		message := Translate(locale, m.key, m.args...)
+		if detail, ok := strings.CutPrefix(err.Error(), m.err.Error()+": "); ok && detail != "" {
+			message += ": " + detail
+		}
+		return message
+	}
+	return err.Error()
+}
+
+// Функция разбора шаблонов страниц с функциями перевода для языка запроса:
+// T - перевод по ключу, lang - код языка, langURL - текущая страница на другом языке,
+// languages - список языков для переключателя, date и datetime - даты в формате языка,
+// country и place - переведенные названия страны и локации из ключей API
+func parseTemplates(r *http.Request) (*template.Template, error) {
+	locale := RequestLocale(r)
+
+	funcs := template.FuncMap{
+		"T": func(key string, args ...interface{}) string {
+			return Translate(locale, key, args...)
+		},
+		"lang": func() string {
+			return locale
+		},
+		"langURL": func(code string) string {
+			u := *r.URL
+			q := u.Query()
+			q.Set(localeParam, code)
+			u.RawQuery = q.Encode()
+			return u.RequestURI()
+		},
+		"date": func(v interface{}) string {
+			switch d := v.(type) {
+			case time.Time:
+				return FormatDate(locale, d, DateMedium)
+			case string:
+				return FormatConcertDate(locale, d, DateMedium)
+			default:
+				return fmt.Sprint(v)
+			}
+		},
+		"datetime": func(t time.Time) string {
+			return FormatDateTime(locale, t)
+		},
+		"country": func(key string) string {
+			return CountryName(locale, key)
+		},
+		"place": func(location string) string {
+			return PlaceName(locale, location)
+		},
+		"nonce": func() string {
+			return Nonce(r)
+		},
+		"languages": func() []Language {
+			languages := make([]Language, 0, len(supportedLocales))
+			for _, code := range supportedLocales {
+				languages = append(languages, Language{Code: code, Name: Translate(code, "language.name")})
+			}
+			return languages
+		},
+	}
+
+	return template.New("").Funcs(funcs).ParseGlob("./web/templates/*.html")
+}
@@ -0,0 +1,305 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultImageCacheDir = "imagecache"
+	thumbnailSize        = 225
+	maxImageBytes        = 5 << 20
+	maxImagePixels       = 4096 * 4096
+	imageMaxAge          = 7 * 24 * time.Hour
+	placeholderMaxAge    = time.Minute
+	placeholderImage     = "web/static/img/placeholder.svg"
+)
+
+// Допустимые типы изображений и расширения файлов в кэше
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+var (
+	ErrInvalidImage = errors.New("некорректное изображение")
+
+	imageClient = &http.Client{Timeout: 15 * time.Second}
+	imageLocks  sync.Map
+	// Неудачные загрузки запоминаются, чтобы не ждать источник при каждом запросе
+	imageFailures sync.Map
+)
+
+// Неудачная загрузка изображения и ее время
+type imageFailure struct {
+	err error
+	at  time.Time
+}
+
+// Функция получения каталога кэша изображений из переменной окружения IMAGE_CACHE_DIR
+func imageCacheDir() string {
+	if dir := os.Getenv("IMAGE_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return defaultImageCacheDir
+}
+
+// Функция получения имени файла в кэше: номер группы и хэш адреса,
+// чтобы при смене адреса изображение загружалось заново
+func imageCacheKey(band Band) string {
+	sum := sha1.Sum([]byte(band.Image))
+	return strconv.Itoa(band.ID) + "-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Функция поиска изображения в кэше
+func findCachedImage(key string) (string, bool) {
+	for _, ext := range imageTypes {
+		path := filepath.Join(imageCacheDir(), key+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Функция загрузки изображения группы с проверкой типа содержимого
+func downloadImage(rawURL string) ([]byte, string, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return nil, "", fmt.Errorf("%w: адрес %q", ErrInvalidImage, rawURL)
+	}
+
+	resp, err := imageClient.Get(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%w: статус %v", ErrInvalidImage, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageBytes {
+		return nil, "", fmt.Errorf("%w: размер больше %d байт", ErrInvalidImage, maxImageBytes)
+	}
+
+	// Тип из заголовка должен совпадать с фактическим содержимым
+	declared := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	detected := http.DetectContentType(data)
+	if _, ok := imageTypes[detected]; !ok || (declared != "" && declared != detected) {
+		return nil, "", fmt.Errorf("%w: тип %q, содержимое %q", ErrInvalidImage, declared, detected)
+	}
+
+	if err := checkImageSize(bytes.NewReader(data)); err != nil {
+		return nil, "", err
+	}
+
+	return data, detected, nil
+}
+
+// Функция проверки размеров изображения по заголовку без полного декодирования
+func checkImageSize(r io.Reader) error {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return fmt.Errorf("%w: размеры %dx%d", ErrInvalidImage, config.Width, config.Height)
+	}
+	return nil
+}
+
+// Функция получения пути к изображению группы в кэше, при необходимости с загрузкой
+func cachedImage(band Band, thumb bool) (string, error) {
+	key := imageCacheKey(band)
+
+	// Одно изображение загружается только одним запросом одновременно
+	lock, _ := imageLocks.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	original, ok := findCachedImage(key)
+	if !ok {
+		// Повторная попытка не раньше, чем истечет срок кэширования заглушки
+		if failure, ok := imageFailures.Load(key); ok && time.Since(failure.(imageFailure).at) < placeholderMaxAge {
+			return "", failure.(imageFailure).err
+		}
+		data, contentType, err := downloadImage(band.Image)
+		if err != nil {
+			imageFailures.Store(key, imageFailure{err: err, at: time.Now()})
+			return "", err
+		}
+		imageFailures.Delete(key)
+		if err := os.MkdirAll(imageCacheDir(), 0o755); err != nil {
+			return "", err
+		}
+		original = filepath.Join(imageCacheDir(), key+imageTypes[contentType])
+		if err := replaceFile(original, data, 0o644); err != nil {
+			return "", err
+		}
+		log.Println("Изображение группы", band.ID, "сохранено в кэш")
+	}
+
+	if !thumb {
+		return original, nil
+	}
+
+	thumbPath := filepath.Join(imageCacheDir(), key+"-thumb.jpg")
+	if _, err := os.Stat(thumbPath); err == nil {
+		return thumbPath, nil
+	}
+
+	if err := makeThumbnail(original, thumbPath); err != nil {
+		return "", err
+	}
+
+	return thumbPath, nil
+}
+
+// Функция создания уменьшенной квадратной копии изображения в формате JPEG
+func makeThumbnail(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := checkImageSize(f); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Thumbnail(img, thumbnailSize), &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+
+	return replaceFile(dst, buf.Bytes(), 0o644)
+}
+
+// Функция уменьшения изображения: центральный квадрат, уменьшенный усреднением пикселей
+func Thumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	// Маленькие изображения не увеличиваются
+	if side < size {
+		size = side
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0, sy1 := y0+y*side/size, y0+(y+1)*side/size
+		for x := 0; x < size; x++ {
+			sx0, sx1 := x0+x*side/size, x0+(x+1)*side/size
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// Функция отправки заглушки вместо изображения
+func servePlaceholder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(placeholderMaxAge.Seconds())))
+	http.ServeFile(w, r, placeholderImage)
+}
+
+func ImageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Адреса вида /img/{id} и /img/{id}/thumb
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/img/"), "/"), "/")
+	if len(parts) > 2 || (len(parts) == 2 && parts[1] != "thumb") {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	numID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	band, ok := bandByID(numID)
+	if !ok {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	path, err := cachedImage(band, len(parts) == 2)
+	if err != nil {
+		log.Println("Не удалось получить изображение группы", band.ID, ":", err)
+		servePlaceholder(w, r)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Println(err)
+		servePlaceholder(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		log.Println(err)
+		servePlaceholder(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(imageMaxAge.Seconds())))
+	w.Header().Set("ETag", `"`+strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+`"`)
+	http.ServeContent(w, r, path, info.ModTime(), f)
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package pkg
+
+import (
+	"errors"
+	"os"
+)
+
+// Функция получения исключительной блокировки файла: без flock файл блокировки
+// создается с O_EXCL и после аварийного завершения процесса его нужно удалить вручную
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrStoreLocked
+	}
+	return file, err
+}
+
+// Функция снятия блокировки файла
+func unlockFile(file *os.File) error {
+	err := file.Close()
+	if errRemove := os.Remove(file.Name()); err == nil {
+		err = errRemove
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package pkg
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Функция получения исключительной блокировки файла без ожидания.
+// Блокировка flock снимается системой и при аварийном завершении процесса.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrStoreLocked
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Функция снятия блокировки файла
+func unlockFile(file *os.File) error {
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return file.Close()
+}
@@ -0,0 +1,395 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	kvOpPut          = "put"
+	kvOpDelete       = "del"
+	kvOpDropBucket   = "drop"
+	kvOpCommit       = "commit"
+	kvCompactMinimum = 1000
+)
+
+var (
+	ErrStoreReadOnly = errors.New("хранилище открыто только для чтения")
+	ErrStoreClosed   = errors.New("хранилище закрыто")
+	ErrStoreCorrupt  = errors.New("журнал хранилища поврежден")
+	ErrStoreLocked   = errors.New("хранилище уже открыто для записи другим процессом")
+)
+
+// Встроенное хранилище ключ-значение.
+// Данные хранятся в памяти и в файле-журнале: каждая транзакция дописывается
+// в конец файла строками JSON и завершается записью commit. Незавершенная
+// транзакция в конце файла (например, после сбоя) при открытии отбрасывается.
+// Открыть хранилище для записи может только один процесс: на время работы
+// берется блокировка файла path.lock.
+type KVStore struct {
+	mu       sync.RWMutex
+	path     string
+	file     *os.File
+	lock     *os.File
+	readOnly bool
+	buckets  map[string]map[string]json.RawMessage
+	// Количество записей в журнале, нужно для решения о сжатии
+	records int
+	// Длина журнала после последней успешной записи
+	size int64
+}
+
+// Запись журнала хранилища
+type kvRecord struct {
+	Op     string          `json:"op"`
+	Bucket string          `json:"b,omitempty"`
+	Key    string          `json:"k,omitempty"`
+	Value  json.RawMessage `json:"v,omitempty"`
+}
+
+// Транзакция записи, изменения применяются только при успешном завершении
+type KVTx struct {
+	store   *KVStore
+	records []kvRecord
+}
+
+// Функция открытия хранилища, файл создается при отсутствии
+func OpenKVStore(path string, readOnly bool) (*KVStore, error) {
+	s := &KVStore{path: path, readOnly: readOnly, buckets: map[string]map[string]json.RawMessage{}}
+
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	} else {
+		// Блокируется отдельный файл: журнал при сжатии заменяется новым файлом,
+		// и блокировка старого уже не мешала бы другому процессу
+		lock, err := lockFile(path + ".lock")
+		if err != nil {
+			return nil, fmt.Errorf("хранилище %v: %w", path, err)
+		}
+		s.lock = lock
+	}
+	file, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		s.unlock()
+		return nil, err
+	}
+
+	valid, err := s.replay(file)
+	if err != nil {
+		file.Close()
+		s.unlock()
+		return nil, fmt.Errorf("хранилище %v: %w", path, err)
+	}
+
+	if readOnly {
+		file.Close()
+		return s, nil
+	}
+
+	// Отбрасываем незавершенную транзакцию в конце файла
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		s.unlock()
+		return nil, err
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		file.Close()
+		s.unlock()
+		return nil, err
+	}
+	s.file = file
+	s.size = valid
+
+	return s, nil
+}
+
+// Функция снятия блокировки хранилища
+func (s *KVStore) unlock() {
+	if s.lock != nil {
+		unlockFile(s.lock)
+		s.lock = nil
+	}
+}
+
+// Функция чтения журнала, возвращает длину корректной части файла.
+// Отбрасывается только оборванная последняя строка и транзакция без commit;
+// некорректная строка с переводом строки означает повреждение журнала,
+// и отбросить ее вместе со всеми следующими транзакциями нельзя.
+func (s *KVStore) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+
+	var (
+		offset, valid int64
+		pending       []kvRecord
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Строка без перевода строки в конце файла - оборванная запись
+			return valid, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(len(line))
+
+		var rec kvRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return 0, fmt.Errorf("%w: строка на смещении %d: %v", ErrStoreCorrupt, offset-int64(len(line)), err)
+		}
+		if rec.Op != kvOpCommit {
+			pending = append(pending, rec)
+			continue
+		}
+
+		s.apply(pending)
+		s.records += len(pending)
+		pending = nil
+		valid = offset
+	}
+}
+
+// Функция применения записей к данным в памяти, вызывается под s.mu
+func (s *KVStore) apply(records []kvRecord) {
+	for _, rec := range records {
+		switch rec.Op {
+		case kvOpPut:
+			bucket := s.buckets[rec.Bucket]
+			if bucket == nil {
+				bucket = map[string]json.RawMessage{}
+				s.buckets[rec.Bucket] = bucket
+			}
+			bucket[rec.Key] = rec.Value
+		case kvOpDelete:
+			delete(s.buckets[rec.Bucket], rec.Key)
+		case kvOpDropBucket:
+			delete(s.buckets, rec.Bucket)
+		}
+	}
+}
+
+// Функция чтения значения по ключу, возвращает false если ключа нет
+func (s *KVStore) Get(bucket, key string, v interface{}) (bool, error) {
+	s.mu.RLock()
+	raw, ok := s.buckets[bucket][key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Функция обхода всех значений раздела в порядке ключей
+func (s *KVStore) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	values := make(map[string]json.RawMessage, len(s.buckets[bucket]))
+	for k, v := range s.buckets[bucket] {
+		keys = append(keys, k)
+		values[k] = v
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fn(k, values[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Функция получения количества ключей в разделе
+func (s *KVStore) Len(bucket string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.buckets[bucket])
+}
+
+// Функция выполнения транзакции записи.
+// Если fn возвращает ошибку, изменения не сохраняются.
+func (s *KVStore) Update(fn func(tx *KVTx) error) error {
+	if s.readOnly {
+		return ErrStoreReadOnly
+	}
+
+	tx := &KVTx{store: s}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.records) == 0 {
+		return nil
+	}
+
+	var buf []byte
+	for _, rec := range append(tx.records, kvRecord{Op: kvOpCommit}) {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	if err := s.write(buf); err != nil {
+		return err
+	}
+
+	s.apply(tx.records)
+	s.records += len(tx.records)
+
+	if s.records > kvCompactMinimum && s.records > 2*s.liveRecords() {
+		return s.compact()
+	}
+	return nil
+}
+
+// Функция записи транзакции в конец журнала, вызывается под s.mu.
+// При ошибке записанная часть удаляется: иначе следующая транзакция
+// оказалась бы после оборванной строки и была бы потеряна при открытии.
+func (s *KVStore) write(buf []byte) error {
+	_, err := s.file.Write(buf)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		if errTrunc := s.file.Truncate(s.size); errTrunc != nil {
+			return fmt.Errorf("%v; не удалось отменить запись: %v", err, errTrunc)
+		}
+		if _, errSeek := s.file.Seek(s.size, io.SeekStart); errSeek != nil {
+			return fmt.Errorf("%v; не удалось отменить запись: %v", err, errSeek)
+		}
+		return err
+	}
+
+	s.size += int64(len(buf))
+	return nil
+}
+
+// Функция записи значения в транзакции
+func (tx *KVTx) Put(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tx.records = append(tx.records, kvRecord{Op: kvOpPut, Bucket: bucket, Key: key, Value: raw})
+	return nil
+}
+
+// Функция удаления значения в транзакции
+func (tx *KVTx) Delete(bucket, key string) {
+	tx.records = append(tx.records, kvRecord{Op: kvOpDelete, Bucket: bucket, Key: key})
+}
+
+// Функция удаления всего раздела в транзакции
+func (tx *KVTx) DropBucket(bucket string) {
+	tx.records = append(tx.records, kvRecord{Op: kvOpDropBucket, Bucket: bucket})
+}
+
+// Функция подсчета актуальных записей, вызывается под s.mu
+func (s *KVStore) liveRecords() int {
+	n := 0
+	for _, bucket := range s.buckets {
+		n += len(bucket)
+	}
+	return n
+}
+
+// Функция сжатия журнала: файл перезаписывается только актуальными данными
+func (s *KVStore) Compact() error {
+	if s.readOnly {
+		return ErrStoreReadOnly
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.compact()
+}
+
+// Функция сжатия журнала, вызывается под s.mu
+func (s *KVStore) compact() error {
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	names := make([]string, 0, len(s.buckets))
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for key, value := range s.buckets[name] {
+			if err := enc.Encode(kvRecord{Op: kvOpPut, Bucket: name, Key: key, Value: value}); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+	}
+	if err := enc.Encode(kvRecord{Op: kvOpCommit}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	s.file.Close()
+	s.file = tmp
+	s.size = size
+	s.records = s.liveRecords()
+
+	return nil
+}
+
+// Функция закрытия хранилища
+func (s *KVStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	s.unlock()
+	return err
+}
@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"strings"
+)
+
+// Сокращения стран, которые пишутся заглавными буквами
+var countryAbbreviations = map[string]bool{"usa": true, "uk": true, "uae": true}
+
+// Коды стран ISO 3166 по ключам стран из API: по ним берутся переведенные названия
+var countryCodes = map[string]string{
+	"argentina": "AR", "australia": "AU", "austria": "AT", "belarus": "BY", "belgium": "BE",
+	"brazil": "BR", "bulgaria": "BG", "canada": "CA", "chile": "CL", "china": "CN",
+	"colombia": "CO", "costa_rica": "CR", "czech_republic": "CZ", "czechia": "CZ", "denmark": "DK",
+	"egypt": "EG", "estonia": "EE", "finland": "FI", "france": "FR", "french_polynesia": "PF",
+	"germany": "DE", "greece": "GR", "hungary": "HU", "iceland": "IS", "india": "IN",
+	"indonesia": "ID", "ireland": "IE", "israel": "IL", "italy": "IT", "japan": "JP",
+	"latvia": "LV", "lithuania": "LT", "mexico": "MX", "netherlands": "NL", "new_caledonia": "NC",
+	"new_zealand": "NZ", "norway": "NO", "peru": "PE", "philippines": "PH", "poland": "PL",
+	"portugal": "PT", "qatar": "QA", "romania": "RO", "russia": "RU", "saudi_arabia": "SA",
+	"singapore": "SG", "slovakia": "SK", "south_africa": "ZA", "south_korea": "KR", "spain": "ES",
+	"sweden": "SE", "switzerland": "CH", "taiwan": "TW", "thailand": "TH", "turkey": "TR",
+	"uae": "AE", "uk": "GB", "ukraine": "UA", "united_arab_emirates": "AE", "uruguay": "UY",
+	"usa": "US", "venezuela": "VE",
+}
+
+// Функция разбора локации вида "north_carolina-usa" на место и страну
+func ParseLocation(location string) (place, country string) {
+	location = strings.ToLower(strings.TrimSpace(location))
+
+	i := strings.LastIndex(location, "-")
+	if i < 0 {
+		return "", location
+	}
+
+	return location[:i], location[i+1:]
+}
+
+// Функция получения читаемого названия из ключа вида "north_carolina"
+func HumanizeKey(key string) string {
+	if countryAbbreviations[key] {
+		return strings.ToUpper(key)
+	}
+
+	words := strings.Fields(strings.NewReplacer("_", " ", "-", " ").Replace(key))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// Функция получения читаемого названия локации: "North Carolina, USA"
+func LocationName(location string) string {
+	place, country := ParseLocation(location)
+	if place == "" {
+		return HumanizeKey(country)
+	}
+	return HumanizeKey(place) + ", " + HumanizeKey(country)
+}
@@ -0,0 +1,164 @@
+package pkg
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Участник групп
+type Member struct {
+	Slug  string    `json:"slug"`
+	Name  string    `json:"name"`
+	Bands []BandRef `json:"bands"`
+}
+
+// Группа, связанная с другой группой через общих участников
+type RelatedBand struct {
+	BandRef
+	SharedMembers []string
+}
+
+// Функция получения части адреса из имени: "Freddie Mercury" -> "freddie-mercury"
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// Функция объединения участников всех групп по имени
+func BuildMembers(bands []Band) map[string]*Member {
+	members := make(map[string]*Member)
+
+	for _, b := range bands {
+		for _, name := range b.Members {
+			slug := Slugify(name)
+			if slug == "" {
+				continue
+			}
+			m, ok := members[slug]
+			if !ok {
+				m = &Member{Slug: slug, Name: strings.TrimSpace(name)}
+				members[slug] = m
+			}
+			m.Bands = append(m.Bands, BandRef{ID: b.ID, Name: b.Name})
+		}
+	}
+
+	return members
+}
+
+// Функция получения участников группы со ссылками на их страницы
+func bandMembers(band Band, members map[string]*Member) []Member {
+	result := make([]Member, 0, len(band.Members))
+	for _, name := range band.Members {
+		if m, ok := members[Slugify(name)]; ok {
+			result = append(result, *m)
+		} else {
+			result = append(result, Member{Name: name})
+		}
+	}
+	return result
+}
+
+// Функция поиска групп, у которых есть общие участники с данной группой
+func relatedBands(band Band, members map[string]*Member) []RelatedBand {
+	related := make(map[int]*RelatedBand)
+
+	for _, name := range band.Members {
+		m, ok := members[Slugify(name)]
+		if !ok {
+			continue
+		}
+		for _, other := range m.Bands {
+			if other.ID == band.ID {
+				continue
+			}
+			rb, ok := related[other.ID]
+			if !ok {
+				rb = &RelatedBand{BandRef: other}
+				related[other.ID] = rb
+			}
+			rb.SharedMembers = append(rb.SharedMembers, m.Name)
+		}
+	}
+
+	result := make([]RelatedBand, 0, len(related))
+	for _, rb := range related {
+		result = append(result, *rb)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].SharedMembers) != len(result[j].SharedMembers) {
+			return len(result[i].SharedMembers) > len(result[j].SharedMembers)
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Данные для страниц участников
+type membersPage struct {
+	Members []Member
+	Member  *Member
+}
+
+func MembersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/members" && !strings.HasPrefix(r.URL.Path, "/members/") {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	slug := strings.Trim(strings.TrimPrefix(r.URL.Path, "/members"), "/")
+	if strings.Contains(slug, "/") {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	bandInfoMu.RLock()
+	members := BuildMembers(ResponseData.Band)
+	bandInfoMu.RUnlock()
+
+	var page membersPage
+	if slug == "" {
+		for _, m := range members {
+			page.Members = append(page.Members, *m)
+		}
+		sort.Slice(page.Members, func(i, j int) bool { return page.Members[i].Name < page.Members[j].Name })
+	} else {
+		m, ok := members[strings.ToLower(slug)]
+		if !ok {
+			NotFoundHandler(w, r, http.StatusNotFound)
+			return
+		}
+		page.Member = m
+	}
+
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+	err = templates.ExecuteTemplate(w, "members.html", &page)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Описание JSON API в формате OpenAPI 3
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Функция получения описания API в формате OpenAPI 3
+func OpenAPIDocument() []byte {
+	return append([]byte(nil), openAPIDocument...)
+}
+
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/openapi.json" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(openAPIDocument)
+}
@@ -0,0 +1,256 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+var ErrInvalidListOptions = errors.New("некорректные параметры сортировки или страницы")
+
+// Поля, по которым можно сортировать группы
+var bandSortKeys = map[string]bool{
+	"id":           true,
+	"name":         true,
+	"creationDate": true,
+	"firstAlbum":   true,
+	"members":      true,
+	"concerts":     true,
+}
+
+// Параметры сортировки и постраничного вывода
+type ListOptions struct {
+	Sort    string
+	Desc    bool
+	Page    int
+	PerPage int
+	// Смещение, полученное из курсора (для API)
+	offset    int
+	hasCursor bool
+}
+
+// Страница списка групп
+type BandPage struct {
+	Items      []Band `json:"items"`
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	TotalPages int    `json:"totalPages"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Функция разбора параметров sort, order, page, per_page и cursor из запроса
+func ParseListOptions(r *http.Request) (ListOptions, error) {
+	q := r.URL.Query()
+	opts := ListOptions{Sort: "id", Page: 1, PerPage: defaultPerPage}
+
+	if s := q.Get("sort"); s != "" {
+		if !bandSortKeys[s] {
+			return opts, fmt.Errorf("%w: sort=%q", ErrInvalidListOptions, s)
+		}
+		opts.Sort = s
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("%w: order=%q", ErrInvalidListOptions, q.Get("order"))
+	}
+
+	if s := q.Get("per_page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPerPage {
+			return opts, fmt.Errorf("%w: per_page=%q", ErrInvalidListOptions, s)
+		}
+		opts.PerPage = n
+	}
+
+	if s := q.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return opts, fmt.Errorf("%w: page=%q", ErrInvalidListOptions, s)
+		}
+		opts.Page = n
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		offset, err := opts.decodeCursor(s)
+		if err != nil {
+			return opts, err
+		}
+		opts.offset, opts.hasCursor = offset, true
+	}
+
+	return opts, nil
+}
+
+// Курсор содержит смещение и сортировку, чтобы его нельзя было применить к другому порядку
+func (o ListOptions) encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s:%t", offset, o.Sort, o.Desc)))
+}
+
+func (o ListOptions) decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: cursor", ErrInvalidListOptions)
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[1] != o.Sort || parts[2] != strconv.FormatBool(o.Desc) {
+		return 0, fmt.Errorf("%w: cursor", ErrInvalidListOptions)
+	}
+
+	offset, err := strconv.Atoi(parts[0])
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: cursor", ErrInvalidListOptions)
+	}
+	return offset, nil
+}
+
+// Функция подсчета концертов группы
+func concertCount(b Band) int {
+	n := 0
+	for _, dates := range b.Relations {
+		n += len(dates)
+	}
+	return n
+}
+
+// Функция сравнения групп по полю сортировки, возвращает -1, 0 или 1
+func compareBands(a, b Band, key string) int {
+	switch key {
+	case "id":
+		return compareInts(a.ID, b.ID)
+	case "name":
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case "creationDate":
+		return compareInts(a.CreationDate, b.CreationDate)
+	case "firstAlbum":
+		// Группы без даты первого альбома оказываются в конце
+		if a.FirstAlbumDate.IsZero() || b.FirstAlbumDate.IsZero() {
+			return compareInts(boolToInt(a.FirstAlbumDate.IsZero()), boolToInt(b.FirstAlbumDate.IsZero()))
+		}
+		return a.FirstAlbumDate.Compare(b.FirstAlbumDate)
+	case "members":
+		return compareInts(len(a.Members), len(b.Members))
+	case "concerts":
+		return compareInts(concertCount(a), concertCount(b))
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Функция сортировки групп; при равенстве порядок определяется номером группы
+func SortBands(bands []Band, key string, desc bool) []Band {
+	sorted := append([]Band(nil), bands...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c := compareBands(sorted[i], sorted[j], key)
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// Функция сортировки и выбора одной страницы групп
+func PaginateBands(bands []Band, opts ListOptions) BandPage {
+	sorted := SortBands(bands, opts.Sort, opts.Desc)
+
+	page := BandPage{
+		Total:      len(sorted),
+		PerPage:    opts.PerPage,
+		TotalPages: (len(sorted) + opts.PerPage - 1) / opts.PerPage,
+	}
+
+	// Смещение считается только для существующих страниц: номер страницы из запроса
+	// может быть сколь угодно большим, и произведение переполнило бы int
+	offset := len(sorted)
+	page.Page = opts.Page
+	switch {
+	case opts.hasCursor:
+		if opts.offset < offset {
+			offset = opts.offset
+		}
+		page.Page = offset/opts.PerPage + 1
+	case opts.Page-1 <= len(sorted)/opts.PerPage:
+		offset = (opts.Page - 1) * opts.PerPage
+	}
+
+	if offset >= len(sorted) {
+		page.Items = []Band{}
+		return page
+	}
+
+	end := offset + opts.PerPage
+	if end > len(sorted) {
+		end = len(sorted)
+	}
+	page.Items = sorted[offset:end]
+
+	if end < len(sorted) {
+		page.NextCursor = opts.encodeCursor(end)
+	}
+
+	return page
+}
+
+// Навигация по страницам для шаблонов
+type Pager struct {
+	Page, TotalPages, Total int
+	Sort, Order             string
+	PrevURL, NextURL        string
+}
+
+// Функция построения навигации со ссылками, сохраняющими остальные параметры запроса
+func newPager(r *http.Request, opts ListOptions, page BandPage) Pager {
+	p := Pager{Page: page.Page, TotalPages: page.TotalPages, Total: page.Total, Sort: opts.Sort, Order: "asc"}
+	if opts.Desc {
+		p.Order = "desc"
+	}
+
+	link := func(n int) string {
+		q := r.URL.Query()
+		q.Del("cursor")
+		q.Set("page", strconv.Itoa(n))
+		return r.URL.Path + "?" + q.Encode()
+	}
+	if page.Page > 1 {
+		p.PrevURL = link(page.Page - 1)
+	}
+	if page.Page < page.TotalPages {
+		p.NextURL = link(page.Page + 1)
+	}
+
+	return p
+}
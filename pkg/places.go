@@ -0,0 +1,208 @@
+package pkg
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Концерты одной группы в одном месте
+type PlaceBand struct {
+	BandRef
+	Dates []string
+}
+
+// Сводка по городу (месту внутри страны)
+type CitySummary struct {
+	Key      string
+	Name     string
+	Bands    []PlaceBand
+	Concerts int
+}
+
+// Сводка по стране
+type CountrySummary struct {
+	Key      string
+	Name     string
+	Cities   []CitySummary
+	Bands    int
+	Concerts int
+}
+
+// Данные для страниц локаций
+type locationsPage struct {
+	Sort      string
+	Countries []CountrySummary
+	Country   *CountrySummary
+	City      *CitySummary
+}
+
+// Функция построения справочника стран и городов по концертам групп
+func BuildPlaces(bands []Band) []CountrySummary {
+	countries := make(map[string]*CountrySummary)
+	cities := make(map[string]*CitySummary)
+	countryBands := make(map[string]map[int]bool)
+
+	for _, b := range bands {
+		for location, dates := range b.Relations {
+			city, country := ParseLocation(location)
+
+			cs, ok := countries[country]
+			if !ok {
+				cs = &CountrySummary{Key: country, Name: HumanizeKey(country)}
+				countries[country] = cs
+				countryBands[country] = make(map[int]bool)
+			}
+			countryBands[country][b.ID] = true
+			cs.Concerts += len(dates)
+
+			c, ok := cities[location]
+			if !ok {
+				c = &CitySummary{Key: city, Name: HumanizeKey(city)}
+				cities[location] = c
+			}
+			c.Concerts += len(dates)
+			c.Bands = append(c.Bands, PlaceBand{BandRef: BandRef{ID: b.ID, Name: b.Name}, Dates: sortDates(dates)})
+		}
+	}
+
+	for location, c := range cities {
+		_, country := ParseLocation(location)
+		sort.Slice(c.Bands, func(i, j int) bool { return c.Bands[i].Name < c.Bands[j].Name })
+		countries[country].Cities = append(countries[country].Cities, *c)
+	}
+
+	result := make([]CountrySummary, 0, len(countries))
+	for key, cs := range countries {
+		cs.Bands = len(countryBands[key])
+		result = append(result, *cs)
+	}
+
+	return result
+}
+
+// Функция сортировки дат концертов в хронологическом порядке
+func sortDates(dates []string) []string {
+	sorted := append([]string(nil), dates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, errA := ParseConcertDate(sorted[i])
+		b, errB := ParseConcertDate(sorted[j])
+		if errA != nil || errB != nil {
+			return errA == nil && errB != nil
+		}
+		return a.Before(b)
+	})
+	return sorted
+}
+
+// Функция сортировки стран: по названию, числу групп или концертов
+func sortCountries(countries []CountrySummary, by string) {
+	sort.Slice(countries, func(i, j int) bool {
+		a, b := countries[i], countries[j]
+		switch {
+		case by == "bands" && a.Bands != b.Bands:
+			return a.Bands > b.Bands
+		case by == "concerts" && a.Concerts != b.Concerts:
+			return a.Concerts > b.Concerts
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Функция сортировки городов: по названию, числу групп или концертов
+func sortCities(cities []CitySummary, by string) {
+	sort.Slice(cities, func(i, j int) bool {
+		a, b := cities[i], cities[j]
+		switch {
+		case by == "bands" && len(a.Bands) != len(b.Bands):
+			return len(a.Bands) > len(b.Bands)
+		case by == "concerts" && a.Concerts != b.Concerts:
+			return a.Concerts > b.Concerts
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Функция проверки параметра сортировки
+func placesSort(r *http.Request) string {
+	switch s := r.URL.Query().Get("sort"); s {
+	case "bands", "concerts":
+		return s
+	}
+	return "name"
+}
+
+func LocationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/locations" && !strings.HasPrefix(r.URL.Path, "/locations/") {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var parts []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/locations"), "/"); rest != "" {
+		parts = strings.Split(strings.ToLower(rest), "/")
+	}
+	if len(parts) > 2 {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	bandInfoMu.RLock()
+	countries := BuildPlaces(ResponseData.Band)
+	bandInfoMu.RUnlock()
+
+	// Названия стран на языке запроса, чтобы сортировка по названию совпадала с выводом
+	locale := RequestLocale(r)
+	for i := range countries {
+		countries[i].Name = CountryName(locale, countries[i].Key)
+	}
+
+	page := locationsPage{Sort: placesSort(r)}
+
+	if len(parts) == 0 {
+		sortCountries(countries, page.Sort)
+		page.Countries = countries
+	} else {
+		for i := range countries {
+			if countries[i].Key == parts[0] {
+				page.Country = &countries[i]
+			}
+		}
+		if page.Country == nil {
+			NotFoundHandler(w, r, http.StatusNotFound)
+			return
+		}
+		sortCities(page.Country.Cities, page.Sort)
+
+		if len(parts) == 2 {
+			for i := range page.Country.Cities {
+				if page.Country.Cities[i].Key == parts[1] {
+					page.City = &page.Country.Cities[i]
+				}
+			}
+			if page.City == nil {
+				NotFoundHandler(w, r, http.StatusNotFound)
+				return
+			}
+		}
+	}
+
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+	err = templates.ExecuteTemplate(w, "locations.html", &page)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,273 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ошибка разбора настройки лимитов запросов
+var ErrInvalidRateLimit = errors.New("некорректный лимит запросов")
+
+// Лимит запросов с одного адреса для маршрутов с общим префиксом:
+// в среднем Rate запросов в секунду и не больше Burst запросов подряд.
+// Rate 0 означает, что маршрут не ограничен.
+type RouteLimit struct {
+	Prefix string
+	Rate   float64
+	Burst  int
+}
+
+// Лимиты по умолчанию. Поиск перебирает все группы, поэтому ограничен сильнее остальных страниц.
+var DefaultRateLimits = []RouteLimit{
+	{Prefix: "/", Rate: 20, Burst: 100},
+	{Prefix: "/search", Rate: 2, Burst: 20},
+	{Prefix: "/api/", Rate: 10, Burst: 50},
+	{Prefix: "/api/search", Rate: 2, Burst: 20},
+	{Prefix: "/graphql", Rate: 5, Burst: 20},
+	{Prefix: "/web/static/", Rate: 0},
+}
+
+// Корзина токенов одного адреса на одном маршруте
+type tokenBucket struct {
+	limit   RouteLimit
+	tokens  float64
+	updated time.Time
+}
+
+// Ограничитель частоты запросов по алгоритму token bucket
+type RateLimiter struct {
+	// Источник текущего времени, в тестах подменяется
+	Now func() time.Time
+
+	mu        sync.Mutex
+	limits    []RouteLimit
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// Функция создания ограничителя; для пути выбирается лимит с самым длинным префиксом
+func NewRateLimiter(limits []RouteLimit) *RateLimiter {
+	sorted := append([]RouteLimit(nil), limits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Prefix) > len(sorted[j].Prefix) })
+
+	return &RateLimiter{
+		Now:     time.Now,
+		limits:  sorted,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Функция разбора лимитов вида "/search=2/s:20,/api/=600/m,/web/static/=off".
+// Число до "/" - количество запросов за секунду (s), минуту (m) или час (h),
+// после ":" - сколько запросов можно сделать подряд (по умолчанию столько же).
+func ParseRateLimits(spec string) ([]RouteLimit, error) {
+	var limits []RouteLimit
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, value, ok := strings.Cut(entry, "=")
+		prefix, value = strings.TrimSpace(prefix), strings.TrimSpace(value)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, entry)
+		}
+		if value == "off" {
+			limits = append(limits, RouteLimit{Prefix: prefix})
+			continue
+		}
+
+		rate, burst, _ := strings.Cut(value, ":")
+		count, unit, ok := strings.Cut(rate, "/")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, entry)
+		}
+
+		limit := RouteLimit{Prefix: prefix, Burst: n}
+		switch unit {
+		case "s":
+			limit.Rate = float64(n)
+		case "m":
+			limit.Rate = float64(n) / 60
+		case "h":
+			limit.Rate = float64(n) / 3600
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, entry)
+		}
+
+		if burst != "" {
+			if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidRateLimit, entry)
+			}
+		}
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// Функция получения лимитов из переменной окружения RATE_LIMITS: ее лимиты заменяют
+// лимиты по умолчанию с тем же префиксом, RATE_LIMITS=off отключает ограничение
+func RateLimitsFromEnv() ([]RouteLimit, error) {
+	spec := strings.TrimSpace(os.Getenv("RATE_LIMITS"))
+	if spec == "off" {
+		return nil, nil
+	}
+
+	custom, err := ParseRateLimits(spec)
+	if err != nil {
+		return DefaultRateLimits, err
+	}
+
+	limits := append([]RouteLimit(nil), custom...)
+	for _, def := range DefaultRateLimits {
+		overridden := false
+		for _, c := range custom {
+			if c.Prefix == def.Prefix {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			limits = append(limits, def)
+		}
+	}
+	return limits, nil
+}
+
+// Функция поиска лимита для пути
+func (l *RateLimiter) limitFor(path string) (RouteLimit, bool) {
+	for _, limit := range l.limits {
+		if strings.HasPrefix(path, limit.Prefix) {
+			return limit, limit.Rate > 0
+		}
+	}
+	return RouteLimit{}, false
+}
+
+// Функция пополнения корзины токенами за прошедшее время
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.updated = now
+	}
+}
+
+// Функция проверки, можно ли выполнить запрос к пути с адреса client.
+// Если нельзя, возвращает время, через которое появится следующий токен.
+func (l *RateLimiter) Allow(path, client string) (bool, time.Duration) {
+	limit, ok := l.limitFor(path)
+	if !ok {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	l.sweep(now)
+
+	key := limit.Prefix + " " + client
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{limit: limit, tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / limit.Rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// Функция удаления полных корзин раз в минуту, чтобы карта не росла с числом адресов
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Функция получения адреса клиента. Заголовок X-Forwarded-For учитывается только
+// с TRUST_PROXY=1, когда сервер стоит за прокси: иначе клиент мог бы подставить любой адрес.
+func ClientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY") == "1" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// Последний адрес добавлен нашим прокси, предыдущие мог передать сам клиент
+			parts := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Функция проверки, что клиент ожидает ответ в формате JSON
+func wantsJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/graphql" ||
+		r.URL.Path == "/openapi.json" || r.URL.Query().Get("format") == "json"
+}
+
+// Функция-обертка, которая ограничивает частоту запросов с одного адреса.
+// На превышение лимита отвечает 429 с заголовком Retry-After: JSON для API, страницей для сайта.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := ClientIP(r)
+
+		ok, wait := l.Allow(r.URL.Path, client)
+		if !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			log.Println("Превышен лимит запросов к", r.URL.Path, "с адреса", client)
+
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			if wantsJSON(r) {
+				writeJSONError(w, r, http.StatusTooManyRequests)
+			} else {
+				renderError(w, r, http.StatusTooManyRequests, Translate(RequestLocale(r), "error.rate_limited", seconds))
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Функция-обертка с лимитами из переменной окружения RATE_LIMITS
+func RateLimit(next http.Handler) http.Handler {
+	limits, err := RateLimitsFromEnv()
+	if err != nil {
+		log.Println("Ошибка в RATE_LIMITS, используются лимиты по умолчанию:", err)
+	}
+	return NewRateLimiter(limits).Middleware(next)
+}
@@ -0,0 +1,511 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDBFile = "groupie.db"
+	SourceDB      = "db"
+
+	bucketMeta      = "meta"
+	bucketBands     = "bands"
+	bucketRelations = "relations"
+	bucketLocations = "locations"
+	bucketMembers   = "members"
+	bucketUsers     = "users"
+	bucketSessions  = "sessions"
+
+	metaSchemaVersion = "schemaVersion"
+	metaSnapshot      = "snapshot"
+)
+
+var (
+	storage   Repository
+	storageMu sync.Mutex
+)
+
+// Хранилище данных трекера: группы, участники, концерты, локации и пользователи
+type Repository interface {
+	// Сохранение снимка данных целиком, старые группы, концерты и локации удаляются
+	SaveSnapshot(s Snapshot) error
+	// Загрузка последнего сохраненного снимка, false если данных еще нет
+	LoadSnapshot() (Snapshot, bool, error)
+
+	Bands() ([]Band, error)
+	Band(id int) (Band, bool, error)
+	Members() ([]Member, error)
+	Concerts(bandID int) ([]Concert, error)
+	Locations() ([]string, error)
+
+	Users() ([]User, error)
+	SaveUser(u User) error
+	Sessions() ([]Session, error)
+	SaveSession(s Session) error
+	DeleteSession(tokenHash string) error
+
+	Close() error
+}
+
+// Сведения о сохраненном снимке
+type snapshotMeta struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+}
+
+// Реализация хранилища поверх встроенного хранилища ключ-значение
+type kvRepository struct {
+	db *KVStore
+}
+
+// Миграция схемы хранилища
+type migration struct {
+	Version int
+	Name    string
+	Apply   func(db *KVStore) error
+}
+
+// Миграции применяются по порядку, номер версии хранится в разделе meta
+var migrations = []migration{
+	{1, "импорт файлов кэша", migrateCacheFiles},
+	{2, "импорт пользователей из users.json", migrateUsersFile},
+}
+
+// Функция получения пути к файлу хранилища из переменной окружения DB_FILE
+func dbFile() string {
+	if f := os.Getenv("DB_FILE"); f != "" {
+		return f
+	}
+	return defaultDBFile
+}
+
+// Функция открытия хранилища и применения миграций
+func OpenRepository(path string, readOnly bool) (Repository, error) {
+	db, err := OpenKVStore(path, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	if !readOnly {
+		if err := migrate(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &kvRepository{db: db}, nil
+}
+
+// Функция открытия хранилища по умолчанию (DB_FILE) для всего приложения.
+// Уже открытое хранилище сначала закрывается, чтобы снять его блокировку.
+func OpenStorage() error {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	if storage != nil {
+		storage.Close()
+		storage = nil
+	}
+
+	repo, err := OpenRepository(dbFile(), false)
+	if err != nil {
+		return err
+	}
+	storage = repo
+
+	return nil
+}
+
+// Функция закрытия хранилища приложения
+func CloseStorage() error {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	if storage == nil {
+		return nil
+	}
+	err := storage.Close()
+	storage = nil
+	return err
+}
+
+// Функция получения хранилища приложения, nil если оно не открыто
+func Storage() Repository {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	return storage
+}
+
+// Функция применения миграций, которые еще не были выполнены
+func migrate(db *KVStore) error {
+	var version int
+	if _, err := db.Get(bucketMeta, metaSchemaVersion, &version); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		if err := m.Apply(db); err != nil {
+			return fmt.Errorf("миграция %d (%v): %w", m.Version, m.Name, err)
+		}
+		if err := db.Update(func(tx *KVTx) error {
+			return tx.Put(bucketMeta, metaSchemaVersion, m.Version)
+		}); err != nil {
+			return err
+		}
+		log.Printf("Применена миграция хранилища %d: %v", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+// Миграция 1: перенос данных из файлов кэша, если в хранилище еще нет групп
+func migrateCacheFiles(db *KVStore) error {
+	if db.Len(bucketBands) > 0 {
+		return nil
+	}
+
+	var s Snapshot
+	if err := readCacheFile(cacheArtistFile, &s.Bands); err != nil || len(s.Bands) == 0 {
+		// Файлов кэша нет или они пустые - переносить нечего
+		return nil
+	}
+	if err := readCacheFile(cacheRelationFile, &s.Relations); err != nil {
+		return nil
+	}
+	if err := readCacheFile(cacheLocationFile, &s.Locations); err != nil {
+		return nil
+	}
+	s.Source = SourceFile
+	if info, err := os.Stat(cacheArtistFile); err == nil {
+		s.Time = info.ModTime()
+	}
+
+	s, _ = ValidateSnapshot(s)
+
+	return (&kvRepository{db: db}).SaveSnapshot(s)
+}
+
+// Миграция 2: перенос пользователей и сессий из файла USERS_FILE
+func migrateUsersFile(db *KVStore) error {
+	var store struct {
+		Users    []User    `json:"users"`
+		Sessions []Session `json:"sessions"`
+	}
+
+	err := readCacheFile(usersFile(), &store)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *KVTx) error {
+		for _, u := range store.Users {
+			if err := tx.Put(bucketUsers, u.ID, u); err != nil {
+				return err
+			}
+		}
+		for _, s := range store.Sessions {
+			if err := tx.Put(bucketSessions, s.TokenHash, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Функция получения ключа группы, номер дополняется нулями для сортировки
+func bandKey(id int) string {
+	return fmt.Sprintf("%010d", id)
+}
+
+// Функция перестроения раздела участников по списку групп
+func putMembers(tx *KVTx, bands []Band) error {
+	tx.DropBucket(bucketMembers)
+	for _, m := range BuildMembers(bands) {
+		if err := tx.Put(bucketMembers, m.Slug, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *kvRepository) SaveSnapshot(s Snapshot) error {
+	return r.db.Update(func(tx *KVTx) error {
+		tx.DropBucket(bucketBands)
+		tx.DropBucket(bucketRelations)
+		tx.DropBucket(bucketLocations)
+
+		for _, b := range s.Bands {
+			if err := tx.Put(bucketBands, bandKey(b.ID), b); err != nil {
+				return err
+			}
+		}
+		for _, rel := range s.Relations.Index {
+			if err := tx.Put(bucketRelations, bandKey(rel.ID), rel.DatesLocations); err != nil {
+				return err
+			}
+		}
+		for _, loc := range s.Locations.Index {
+			if err := tx.Put(bucketLocations, bandKey(loc.ID), loc); err != nil {
+				return err
+			}
+		}
+
+		if err := putMembers(tx, s.Bands); err != nil {
+			return err
+		}
+
+		return tx.Put(bucketMeta, metaSnapshot, snapshotMeta{Time: s.Time, Source: s.Source})
+	})
+}
+
+func (r *kvRepository) LoadSnapshot() (Snapshot, bool, error) {
+	var (
+		s    Snapshot
+		meta snapshotMeta
+	)
+
+	ok, err := r.db.Get(bucketMeta, metaSnapshot, &meta)
+	if err != nil || !ok {
+		return s, false, err
+	}
+	s.Time, s.Source = meta.Time, meta.Source
+
+	err = r.db.ForEach(bucketBands, func(_ string, value []byte) error {
+		var b Band
+		if err := json.Unmarshal(value, &b); err != nil {
+			return err
+		}
+		s.Bands = append(s.Bands, b)
+		return nil
+	})
+	if err != nil {
+		return s, false, err
+	}
+
+	err = r.db.ForEach(bucketRelations, func(key string, value []byte) error {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return err
+		}
+		var dl map[string][]string
+		if err := json.Unmarshal(value, &dl); err != nil {
+			return err
+		}
+		s.Relations.Index = append(s.Relations.Index, struct {
+			ID             int                 `json:"id"`
+			DatesLocations map[string][]string `json:"datesLocations"`
+		}{id, dl})
+		return nil
+	})
+	if err != nil {
+		return s, false, err
+	}
+
+	err = r.db.ForEach(bucketLocations, func(_ string, value []byte) error {
+		var loc struct {
+			ID        int      `json:"id"`
+			Locations []string `json:"locations"`
+			Dates     string   `json:"dates"`
+		}
+		if err := json.Unmarshal(value, &loc); err != nil {
+			return err
+		}
+		s.Locations.Index = append(s.Locations.Index, loc)
+		return nil
+	})
+	if err != nil {
+		return s, false, err
+	}
+
+	return s, true, nil
+}
+
+func (r *kvRepository) Bands() ([]Band, error) {
+	s, _, err := r.LoadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	AddLocationsToBand(s.Bands, s.Locations, s.Relations)
+	ParseFirstAlbums(s.Bands)
+
+	return s.Bands, nil
+}
+
+func (r *kvRepository) Band(id int) (Band, bool, error) {
+	var b Band
+	ok, err := r.db.Get(bucketBands, bandKey(id), &b)
+	if err != nil || !ok {
+		return b, false, err
+	}
+
+	if _, err := r.db.Get(bucketRelations, bandKey(id), &b.Relations); err != nil {
+		return b, false, err
+	}
+	var loc struct {
+		Locations []string `json:"locations"`
+	}
+	if _, err := r.db.Get(bucketLocations, bandKey(id), &loc); err != nil {
+		return b, false, err
+	}
+	b.Locations = loc.Locations
+	b.FirstAlbumDate, _ = ParseFirstAlbum(b.FirstAlbum)
+
+	return b, true, nil
+}
+
+func (r *kvRepository) Members() ([]Member, error) {
+	var members []Member
+	err := r.db.ForEach(bucketMembers, func(_ string, value []byte) error {
+		var m Member
+		if err := json.Unmarshal(value, &m); err != nil {
+			return err
+		}
+		members = append(members, m)
+		return nil
+	})
+	return members, err
+}
+
+func (r *kvRepository) Concerts(bandID int) ([]Concert, error) {
+	var dl map[string][]string
+	if _, err := r.db.Get(bucketRelations, bandKey(bandID), &dl); err != nil {
+		return nil, err
+	}
+	return ConcertsFromRelations(dl), nil
+}
+
+func (r *kvRepository) Locations() ([]string, error) {
+	set := map[string]bool{}
+	err := r.db.ForEach(bucketLocations, func(_ string, value []byte) error {
+		var loc struct {
+			Locations []string `json:"locations"`
+		}
+		if err := json.Unmarshal(value, &loc); err != nil {
+			return err
+		}
+		for _, l := range loc.Locations {
+			set[l] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(set))
+	for l := range set {
+		list = append(list, l)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+func (r *kvRepository) Users() ([]User, error) {
+	var list []User
+	err := r.db.ForEach(bucketUsers, func(_ string, value []byte) error {
+		var u User
+		if err := json.Unmarshal(value, &u); err != nil {
+			return err
+		}
+		list = append(list, u)
+		return nil
+	})
+	return list, err
+}
+
+func (r *kvRepository) SaveUser(u User) error {
+	return r.db.Update(func(tx *KVTx) error {
+		return tx.Put(bucketUsers, u.ID, u)
+	})
+}
+
+func (r *kvRepository) Sessions() ([]Session, error) {
+	var list []Session
+	err := r.db.ForEach(bucketSessions, func(_ string, value []byte) error {
+		var s Session
+		if err := json.Unmarshal(value, &s); err != nil {
+			return err
+		}
+		list = append(list, s)
+		return nil
+	})
+	return list, err
+}
+
+func (r *kvRepository) SaveSession(s Session) error {
+	return r.db.Update(func(tx *KVTx) error {
+		return tx.Put(bucketSessions, s.TokenHash, s)
+	})
+}
+
+func (r *kvRepository) DeleteSession(tokenHash string) error {
+	return r.db.Update(func(tx *KVTx) error {
+		tx.Delete(bucketSessions, tokenHash)
+		return nil
+	})
+}
+
+func (r *kvRepository) Close() error {
+	return r.db.Close()
+}
+
+// Функция сохранения снимка в хранилище приложения, если оно открыто
+func persistSnapshot(s Snapshot) {
+	repo := Storage()
+	if repo == nil {
+		return
+	}
+	if err := repo.SaveSnapshot(s); err != nil {
+		log.Println("Ошибка при сохранении данных в хранилище:", err)
+	}
+}
+
+// Функция загрузки данных из хранилища приложения
+func LoadFromStorage() error {
+	repo := Storage()
+	if repo == nil {
+		return ErrStoreClosed
+	}
+	return loadFromRepository(repo)
+}
+
+// Функция загрузки данных в кэш из хранилища
+func loadFromRepository(repo Repository) error {
+	s, ok, err := repo.LoadSnapshot()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("в хранилище нет данных")
+	}
+	s.Source = SourceDB
+
+	s, report := ValidateSnapshot(s)
+	setQualityReport(report)
+
+	refreshMu.Lock()
+	previous := currentSnapshot()
+	applySnapshot(s)
+	publishChanges(previous, s)
+	refreshMu.Unlock()
+
+	log.Println("Данные из хранилища успешно загружены")
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Группа в ответах JSON API
+type APIBand struct {
+	Band
+	FirstAlbumDate string     `json:"firstAlbumDate,omitempty"`
+	Locations      []string   `json:"locations"`
+	Concerts       []Concert  `json:"concerts"`
+	Tour           *TourStats `json:"tour,omitempty"`
+}
+
+// Функция преобразования группы для JSON API
+func newAPIBand(b Band, withTour bool) APIBand {
+	band := APIBand{Band: b, Locations: b.Locations, Concerts: ConcertsFromRelations(b.Relations)}
+	if !b.FirstAlbumDate.IsZero() {
+		band.FirstAlbumDate = b.FirstAlbumDate.Format("2006-01-02")
+	}
+	if band.Locations == nil {
+		band.Locations = []string{}
+	}
+	if band.Concerts == nil {
+		band.Concerts = []Concert{}
+	}
+	if withTour {
+		tour := ComputeTour(b.Relations)
+		band.Tour = &tour
+	}
+	return band
+}
+
+// Страница групп в ответах JSON API
+type APIBandPage struct {
+	Items      []APIBand `json:"items"`
+	Total      int       `json:"total"`
+	Page       int       `json:"page"`
+	PerPage    int       `json:"perPage"`
+	TotalPages int       `json:"totalPages"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+func newAPIBandPage(page BandPage) APIBandPage {
+	result := APIBandPage{
+		Items:      make([]APIBand, 0, len(page.Items)),
+		Total:      page.Total,
+		Page:       page.Page,
+		PerPage:    page.PerPage,
+		TotalPages: page.TotalPages,
+		NextCursor: page.NextCursor,
+	}
+	for _, b := range page.Items {
+		result.Items = append(result.Items, newAPIBand(b, false))
+	}
+	return result
+}
+
+func APIBandsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/bands" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, err := ParseListOptions(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": LocalizeError(RequestLocale(r), err)})
+		return
+	}
+
+	page := PaginateBands(SnapshotBands(), opts)
+
+	writeJSON(w, http.StatusOK, newAPIBandPage(page))
+}
+
+func APISearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/search" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, err := ParseListOptions(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": LocalizeError(RequestLocale(r), err)})
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	if utf8.RuneCountInString(query) > MaxQueryLength {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": LocalizeError(RequestLocale(r), ErrQueryTooLong)})
+		return
+	}
+
+	bandInfoMu.RLock()
+	found, err := SearchRecords(ResponseData.Band, query)
+	bandInfoMu.RUnlock()
+
+	// Пустой запрос и пустой результат поиска - не ошибка для API,
+	// а некорректный запрос, например дата в поиске по датам, - ошибка
+	var bands []Band
+	if err == nil {
+		bands = *found
+	} else if !errors.Is(err, ErrNoResults) && !errors.Is(err, ErrEmptyQuery) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": LocalizeError(RequestLocale(r), err)})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPIBandPage(PaginateBands(bands, opts)))
+}
+
+func APIBandHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/band" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	numID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	band, ok := bandByID(numID)
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPIBand(band, true))
+}
+
+func APISuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/suggestions" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	bandInfoMu.RLock()
+	search := ResponseData.Search
+	bandInfoMu.RUnlock()
+
+	// Пустые списки отдаем массивами, а не null
+	if search.Names == nil {
+		search.Names = []string{}
+	}
+	if search.CreationDates == nil {
+		search.CreationDates = []int{}
+	}
+	if search.FirstAlbums == nil {
+		search.FirstAlbums = []string{}
+	}
+	if search.Members == nil {
+		search.Members = []string{}
+	}
+	if search.Locations == nil {
+		search.Locations = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, search)
+}
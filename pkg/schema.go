@@ -0,0 +1,460 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	maxGraphQLQueryLength = 10000
+	maxGraphQLBodySize    = 1 << 20
+	// Ожидаемый размер вложенных списков (участники группы, города страны и т.п.)
+	gqlNestedListSize = 10
+)
+
+// Ограничения на запросы GraphQL
+var GraphQLLimits = gqlLimits{MaxDepth: 8, MaxComplexity: 5000, MaxSelections: 2000}
+
+// Данные, на которых выполняется один запрос GraphQL
+type gqlData struct {
+	bands     []Band
+	byID      map[int]Band
+	members   map[string]*Member
+	countries map[string]*CountrySummary
+}
+
+// Концерт группы для GraphQL
+type gqlConcert struct {
+	BandID int
+	Concert
+}
+
+// Данные для страницы GraphQL playground
+type graphQLPage struct {
+	Schema string
+}
+
+// Тело запроса GraphQL
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Ответ GraphQL
+type graphQLResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+func newGQLData(bands []Band) *gqlData {
+	d := &gqlData{bands: bands, byID: make(map[int]Band, len(bands))}
+	for _, b := range bands {
+		d.byID[b.ID] = b
+	}
+	return d
+}
+
+// Функция получения участников, строится при первом обращении
+func (d *gqlData) memberIndex() map[string]*Member {
+	if d.members == nil {
+		d.members = BuildMembers(d.bands)
+	}
+	return d.members
+}
+
+// Функция получения стран, строится при первом обращении
+func (d *gqlData) countryIndex() map[string]*CountrySummary {
+	if d.countries == nil {
+		d.countries = map[string]*CountrySummary{}
+		for _, c := range BuildPlaces(d.bands) {
+			c := c
+			d.countries[c.Key] = &c
+		}
+	}
+	return d.countries
+}
+
+// Функция получения групп по ссылкам в исходном порядке
+func (d *gqlData) bandsByRef(refs []BandRef) []interface{} {
+	list := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		if b, ok := d.byID[ref.ID]; ok {
+			list = append(list, b)
+		}
+	}
+	return list
+}
+
+// Функция выбора части списка по аргументам first и offset
+func gqlSlice(list []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	offset, _ := args["offset"].(int)
+	first, ok := args["first"].(int)
+	if !ok {
+		first = len(list)
+	}
+	if offset < 0 || first < 0 || first > maxPerPage {
+		return nil, errors.New("first must be between 0 and 100 and offset must not be negative")
+	}
+
+	if offset >= len(list) {
+		return []interface{}{}, nil
+	}
+	end := offset + first
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[offset:end], nil
+}
+
+// Аргументы постраничного вывода списков
+var gqlPageArgs = []gqlArgDef{
+	{Name: "first", Type: "Int", Default: defaultPerPage},
+	{Name: "offset", Type: "Int", Default: 0},
+}
+
+func gqlBandList(bands []Band) []interface{} {
+	list := make([]interface{}, len(bands))
+	for i, b := range bands {
+		list[i] = b
+	}
+	return list
+}
+
+func gqlCountry(d *gqlData, key string) interface{} {
+	if c, ok := d.countryIndex()[key]; ok {
+		return *c
+	}
+	return nil
+}
+
+// Функция получения поля-значения без аргументов
+func gqlValue(fn func(src interface{}) interface{}) gqlResolver {
+	return func(_ *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+		return fn(src), nil
+	}
+}
+
+// Схема GraphQL над группами, участниками, концертами и локациями
+var graphQLSchema = &gqlSchema{
+	Query:           "Query",
+	DefaultListSize: defaultPerPage,
+	Types: map[string]*gqlObjectType{
+		"Query": {Name: "Query", Fields: map[string]*gqlFieldDef{
+			"bands": {
+				Type: "Band", List: true,
+				Description: "sort: id, name, creationDate, firstAlbum, members, concerts; search uses the same syntax as /search",
+				Args: append([]gqlArgDef{
+					{Name: "sort", Type: "String", Default: "id"},
+					{Name: "desc", Type: "Boolean", Default: false},
+					{Name: "search", Type: "String"},
+				}, gqlPageArgs...),
+				Resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					key := args["sort"].(string)
+					if !bandSortKeys[key] {
+						return nil, errors.New("unknown sort key " + key)
+					}
+
+					bands := req.data.bands
+					if q, _ := args["search"].(string); q != "" {
+						found, err := SearchRecords(bands, q)
+						if errors.Is(err, ErrQueryTooLong) {
+							return nil, fmt.Errorf("search is longer than %d characters", MaxQueryLength)
+						}
+						if err != nil {
+							return []interface{}{}, nil
+						}
+						bands = *found
+					}
+
+					return gqlSlice(gqlBandList(SortBands(bands, key, args["desc"].(bool))), args)
+				},
+			},
+			"band": {
+				Type: "Band",
+				Args: []gqlArgDef{{Name: "id", Type: "Int", Required: true}},
+				Resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					if b, ok := req.data.byID[args["id"].(int)]; ok {
+						return b, nil
+					}
+					return nil, nil
+				},
+			},
+			"members": {
+				Type: "Member", List: true, Args: gqlPageArgs,
+				Resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					var members []*Member
+					for _, m := range req.data.memberIndex() {
+						members = append(members, m)
+					}
+					sort.Slice(members, func(i, j int) bool { return members[i].Slug < members[j].Slug })
+
+					list := make([]interface{}, len(members))
+					for i, m := range members {
+						list[i] = *m
+					}
+					return gqlSlice(list, args)
+				},
+			},
+			"member": {
+				Type: "Member",
+				Args: []gqlArgDef{{Name: "slug", Type: "String", Required: true}},
+				Resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					if m, ok := req.data.memberIndex()[args["slug"].(string)]; ok {
+						return *m, nil
+					}
+					return nil, nil
+				},
+			},
+			"countries": {
+				Type: "Country", List: true, Args: gqlPageArgs,
+				Resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					var countries []CountrySummary
+					for _, c := range req.data.countryIndex() {
+						countries = append(countries, *c)
+					}
+					sortCountries(countries, "name")
+
+					list := make([]interface{}, len(countries))
+					for i, c := range countries {
+						list[i] = c
+					}
+					return gqlSlice(list, args)
+				},
+			},
+			"country": {
+				Type: "Country",
+				Args: []gqlArgDef{{Name: "key", Type: "String", Required: true}},
+				Resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					return gqlCountry(req.data, strings.ToLower(args["key"].(string))), nil
+				},
+			},
+		}},
+		"Band": {Name: "Band", Fields: map[string]*gqlFieldDef{
+			"id":           {Type: "Int", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Band).ID })},
+			"name":         {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Band).Name })},
+			"image":        {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Band).Image })},
+			"creationDate": {Type: "Int", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Band).CreationDate })},
+			"firstAlbum":   {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Band).FirstAlbum })},
+			"firstAlbumDate": {
+				Type: "String", Description: "ISO 8601 date, null if the upstream date is invalid",
+				Resolve: gqlValue(func(src interface{}) interface{} {
+					if d := src.(Band).FirstAlbumDate; !d.IsZero() {
+						return d.Format("2006-01-02")
+					}
+					return nil
+				}),
+			},
+			"concertCount": {Type: "Int", Resolve: gqlValue(func(src interface{}) interface{} { return concertCount(src.(Band)) })},
+			"members": {
+				Type: "Member", List: true, ListSize: gqlNestedListSize,
+				Resolve: func(req *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					var list []interface{}
+					for _, m := range bandMembers(src.(Band), req.data.memberIndex()) {
+						list = append(list, m)
+					}
+					return list, nil
+				},
+			},
+			"concerts": {
+				Type: "Concert", List: true, Args: gqlPageArgs,
+				Resolve: func(_ *gqlRequest, src interface{}, args map[string]interface{}) (interface{}, error) {
+					b := src.(Band)
+					var list []interface{}
+					for _, c := range ConcertsFromRelations(b.Relations) {
+						list = append(list, gqlConcert{BandID: b.ID, Concert: c})
+					}
+					return gqlSlice(list, args)
+				},
+			},
+		}},
+		"Member": {Name: "Member", Fields: map[string]*gqlFieldDef{
+			"slug": {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Member).Slug })},
+			"name": {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(Member).Name })},
+			"bands": {
+				Type: "Band", List: true, ListSize: gqlNestedListSize,
+				Resolve: func(req *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					return req.data.bandsByRef(src.(Member).Bands), nil
+				},
+			},
+		}},
+		"Concert": {Name: "Concert", Fields: map[string]*gqlFieldDef{
+			"date": {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(gqlConcert).Date })},
+			"isoDate": {
+				Type: "String", Description: "ISO 8601 date, null if the upstream date is invalid",
+				Resolve: gqlValue(func(src interface{}) interface{} {
+					if d, err := ParseConcertDate(src.(gqlConcert).Date); err == nil {
+						return d.Format("2006-01-02")
+					}
+					return nil
+				}),
+			},
+			"location": {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(gqlConcert).Location })},
+			"place":    {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return LocationName(src.(gqlConcert).Location) })},
+			"city": {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} {
+				city, _ := ParseLocation(src.(gqlConcert).Location)
+				return HumanizeKey(city)
+			})},
+			"country": {
+				Type: "Country",
+				Resolve: func(req *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					_, country := ParseLocation(src.(gqlConcert).Location)
+					return gqlCountry(req.data, country), nil
+				},
+			},
+			"band": {
+				Type: "Band",
+				Resolve: func(req *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					if b, ok := req.data.byID[src.(gqlConcert).BandID]; ok {
+						return b, nil
+					}
+					return nil, nil
+				},
+			},
+		}},
+		"Country": {Name: "Country", Fields: map[string]*gqlFieldDef{
+			"key":          {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CountrySummary).Key })},
+			"name":         {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CountrySummary).Name })},
+			"bandCount":    {Type: "Int", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CountrySummary).Bands })},
+			"concertCount": {Type: "Int", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CountrySummary).Concerts })},
+			"cities": {
+				Type: "City", List: true, ListSize: gqlNestedListSize,
+				Resolve: func(_ *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					cities := append([]CitySummary(nil), src.(CountrySummary).Cities...)
+					sortCities(cities, "name")
+					list := make([]interface{}, len(cities))
+					for i, c := range cities {
+						list[i] = c
+					}
+					return list, nil
+				},
+			},
+			"bands": {
+				Type: "Band", List: true, ListSize: gqlNestedListSize,
+				Resolve: func(req *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					seen := map[int]bool{}
+					var refs []BandRef
+					for _, c := range src.(CountrySummary).Cities {
+						for _, b := range c.Bands {
+							if !seen[b.ID] {
+								seen[b.ID] = true
+								refs = append(refs, b.BandRef)
+							}
+						}
+					}
+					sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+					return req.data.bandsByRef(refs), nil
+				},
+			},
+		}},
+		"City": {Name: "City", Fields: map[string]*gqlFieldDef{
+			"key":          {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CitySummary).Key })},
+			"name":         {Type: "String", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CitySummary).Name })},
+			"concertCount": {Type: "Int", Resolve: gqlValue(func(src interface{}) interface{} { return src.(CitySummary).Concerts })},
+			"bands": {
+				Type: "Band", List: true, ListSize: gqlNestedListSize,
+				Resolve: func(req *gqlRequest, src interface{}, _ map[string]interface{}) (interface{}, error) {
+					var refs []BandRef
+					for _, b := range src.(CitySummary).Bands {
+						refs = append(refs, b.BandRef)
+					}
+					return req.data.bandsByRef(refs), nil
+				},
+			},
+		}},
+	},
+}
+
+// Функция проверки режима разработки (переменная окружения DEV_MODE)
+func devMode() bool {
+	v := strings.ToLower(os.Getenv("DEV_MODE"))
+	return v == "1" || v == "true"
+}
+
+// Функция выполнения запроса GraphQL над текущими данными
+func ExecuteGraphQL(query, operationName string, variables map[string]interface{}) (interface{}, []GraphQLError) {
+	bandInfoMu.RLock()
+	data := newGQLData(ResponseData.Band)
+	bandInfoMu.RUnlock()
+
+	return executeGraphQL(graphQLSchema, data, query, operationName, variables, GraphQLLimits)
+}
+
+func GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/graphql" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	var req graphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+
+		if req.Query == "" {
+			// Без запроса показываем playground, но только в режиме разработки
+			if !devMode() {
+				ErrorHandler(w, r, http.StatusNotFound)
+				return
+			}
+			renderGraphQLPlayground(w, r)
+			return
+		}
+
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeJSON(w, http.StatusBadRequest, graphQLResponse{Errors: []GraphQLError{{Message: "variables must be a JSON object"}}})
+				return
+			}
+		}
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxGraphQLBodySize)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Println("Ошибка разбора запроса GraphQL:", err)
+			writeJSON(w, http.StatusBadRequest, graphQLResponse{Errors: []GraphQLError{{Message: "request body must be a JSON object with a query field"}}})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(req.Query) > maxGraphQLQueryLength {
+		writeJSON(w, http.StatusBadRequest, graphQLResponse{Errors: []GraphQLError{{Message: "query is too long"}}})
+		return
+	}
+
+	data, errs := ExecuteGraphQL(req.Query, req.OperationName, req.Variables)
+	if data == nil {
+		writeJSON(w, http.StatusBadRequest, graphQLResponse{Errors: errs})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, graphQLResponse{Data: data, Errors: errs})
+}
+
+// Функция вывода страницы для ручных запросов GraphQL
+func renderGraphQLPlayground(w http.ResponseWriter, r *http.Request) {
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.ExecuteTemplate(w, "graphql.html", &graphQLPage{Schema: graphQLSchema.SDL()})
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
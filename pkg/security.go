@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибка в настройке политики безопасности содержимого
+var ErrInvalidCSPSource = errors.New("некорректный источник в политике безопасности содержимого")
+
+// Срок Strict-Transport-Security по умолчанию
+const defaultHSTSMaxAge = 365 * 24 * time.Hour
+
+// Ключ nonce запроса в контексте
+type nonceKey struct{}
+
+// Заголовки безопасности, которые добавляются ко всем ответам
+type SecurityPolicy struct {
+	// Дополнительные источники изображений для img-src, например хост изображений
+	// из API, если страницы загружают их напрямую, а не через прокси /img/
+	ImageSources []string
+	// Политика Content-Security-Policy целиком вместо политики по умолчанию;
+	// {nonce} в ней заменяется на nonce запроса
+	CSP string
+	// Срок Strict-Transport-Security; 0 отключает заголовок
+	HSTSMaxAge time.Duration
+}
+
+// Функция получения политики из переменных окружения: CSP_IMG_SRC - источники изображений
+// через пробел или запятую, CONTENT_SECURITY_POLICY - политика целиком,
+// HSTS_MAX_AGE - срок Strict-Transport-Security в секундах. При ошибке возвращается политика по умолчанию.
+func SecurityPolicyFromEnv() (*SecurityPolicy, error) {
+	policy := &SecurityPolicy{
+		CSP:        strings.TrimSpace(os.Getenv("CONTENT_SECURITY_POLICY")),
+		HSTSMaxAge: defaultHSTSMaxAge,
+	}
+
+	sources := strings.FieldsFunc(os.Getenv("CSP_IMG_SRC"), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for _, source := range sources {
+		// Точка с запятой или кавычка позволили бы дописать в политику другие директивы
+		if strings.ContainsAny(source, ";'\"") {
+			return &SecurityPolicy{HSTSMaxAge: defaultHSTSMaxAge}, fmt.Errorf("%w: %q", ErrInvalidCSPSource, source)
+		}
+		policy.ImageSources = append(policy.ImageSources, source)
+	}
+
+	if value := os.Getenv("HSTS_MAX_AGE"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return &SecurityPolicy{HSTSMaxAge: defaultHSTSMaxAge}, fmt.Errorf("некорректный HSTS_MAX_AGE: %q", value)
+		}
+		policy.HSTSMaxAge = time.Duration(seconds) * time.Second
+	}
+
+	return policy, nil
+}
+
+// Функция получения Content-Security-Policy для запроса с указанным nonce.
+// Скрипты и стили загружаются только с сайта, встроенные скрипты - только с nonce,
+// изображения - с сайта (в том числе через прокси /img/) и из ImageSources.
+func (p *SecurityPolicy) ContentSecurityPolicy(nonce string) string {
+	if p.CSP != "" {
+		return strings.ReplaceAll(p.CSP, "{nonce}", nonce)
+	}
+
+	script := "script-src 'self'"
+	if nonce != "" {
+		script += " 'nonce-" + nonce + "'"
+	}
+
+	directives := []string{
+		"default-src 'self'",
+		script,
+		"style-src 'self'",
+		strings.Join(append([]string{"img-src 'self'"}, p.ImageSources...), " "),
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+	}
+	return strings.Join(directives, "; ")
+}
+
+// Функция создания случайного nonce для встроенных скриптов
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// base64url без "=": шаблоны выводят nonce в атрибут без экранирования
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Функция получения nonce запроса; шаблоны подставляют его в тег script
+func Nonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(nonceKey{}).(string)
+	return nonce
+}
+
+// Функция-обертка, которая добавляет заголовки безопасности к каждому ответу,
+// в том числе к страницам ошибок. Strict-Transport-Security отправляется только по HTTPS.
+func (p *SecurityPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce, err := newNonce()
+		if err != nil {
+			log.Println("Ошибка при создании nonce:", err)
+		} else {
+			r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce))
+		}
+
+		header := w.Header()
+		header.Set("Content-Security-Policy", p.ContentSecurityPolicy(nonce))
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if p.HSTSMaxAge > 0 && isHTTPS(r) {
+			header.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(p.HSTSMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Функция-обертка с политикой из переменных окружения
+func SecurityHeaders(next http.Handler) http.Handler {
+	policy, err := SecurityPolicyFromEnv()
+	if err != nil {
+		log.Println("Ошибка в настройке заголовков безопасности:", err)
+	}
+	return policy.Middleware(next)
+}
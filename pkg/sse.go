@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	sseClientBuffer   = 8
+	sseHeartbeatEvery = 30 * time.Second
+)
+
+// Сообщение об изменении данных для открытых страниц
+type UpdateEvent struct {
+	Version      int64 `json:"version"`
+	ChangedBands []int `json:"changedBands"`
+	AddedBands   []int `json:"addedBands"`
+	RemovedBands []int `json:"removedBands"`
+}
+
+// Клиент потока событий
+type sseClient struct {
+	events chan UpdateEvent
+	done   chan struct{}
+}
+
+// Максимальное число одновременно открытых потоков событий: каждый держит
+// соединение и горутину, поэтому сверх предела клиенты получают 503
+var MaxEventClients = 1000
+
+var (
+	sseClients   = make(map[*sseClient]bool)
+	sseClientsMu sync.Mutex
+)
+
+func init() {
+	OnRefresh(func(diff SnapshotDiff, current Snapshot) {
+		broadcastUpdate(newUpdateEvent(SnapshotVersion(), diff))
+	})
+}
+
+// Функция формирования сообщения из разницы снимков
+func newUpdateEvent(version int64, diff SnapshotDiff) UpdateEvent {
+	event := UpdateEvent{Version: version, ChangedBands: []int{}, AddedBands: []int{}, RemovedBands: []int{}}
+	for _, c := range diff.Changed {
+		event.ChangedBands = append(event.ChangedBands, c.ID)
+	}
+	for _, b := range diff.AddedBands {
+		event.AddedBands = append(event.AddedBands, b.ID)
+	}
+	for _, b := range diff.RemovedBands {
+		event.RemovedBands = append(event.RemovedBands, b.ID)
+	}
+	return event
+}
+
+// Функция рассылки сообщения всем клиентам.
+// Клиент, не успевающий читать сообщения, отключается и переподключается сам.
+func broadcastUpdate(event UpdateEvent) {
+	sseClientsMu.Lock()
+	defer sseClientsMu.Unlock()
+
+	for client := range sseClients {
+		select {
+		case client.events <- event:
+		default:
+			log.Println("Клиент потока событий не успевает читать сообщения и будет отключен")
+			delete(sseClients, client)
+			close(client.done)
+		}
+	}
+}
+
+// Функция подключения клиента, возвращает false, если достигнут предел MaxEventClients
+func addSSEClient() (*sseClient, bool) {
+	client := &sseClient{
+		events: make(chan UpdateEvent, sseClientBuffer),
+		done:   make(chan struct{}),
+	}
+
+	sseClientsMu.Lock()
+	defer sseClientsMu.Unlock()
+
+	if len(sseClients) >= MaxEventClients {
+		return nil, false
+	}
+	sseClients[client] = true
+
+	return client, true
+}
+
+func removeSSEClient(client *sseClient) {
+	sseClientsMu.Lock()
+	defer sseClientsMu.Unlock()
+
+	if sseClients[client] {
+		delete(sseClients, client)
+		close(client.done)
+	}
+}
+
+// Функция получения числа подключенных клиентов потока событий
+func EventClientCount() int {
+	sseClientsMu.Lock()
+	defer sseClientsMu.Unlock()
+
+	return len(sseClients)
+}
+
+// Функция записи одного события в поток
+func writeSSE(w http.ResponseWriter, name string, id int64, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\nid: %d\ndata: %s\n\n", name, id, data)
+	return err
+}
+
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/events" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	client, ok := addSSEClient()
+	if !ok {
+		log.Println("Достигнут предел клиентов потока событий:", MaxEventClients)
+		w.Header().Set("Retry-After", "30")
+		ErrorHandler(w, r, http.StatusServiceUnavailable)
+		return
+	}
+	defer removeSSEClient(client)
+
+	rc := http.NewResponseController(w)
+	// Поток событий живет дольше, чем WriteTimeout сервера
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		log.Println(err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	version := SnapshotVersion()
+	if err := writeSSE(w, "hello", version, map[string]int64{"version": version}); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		log.Println(err)
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatEvery)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client.done:
+			return
+		case event := <-client.events:
+			if err := writeSSE(w, "update", event.Version, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
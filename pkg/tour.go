@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	concertDateLayout = "02-01-2006"
+	earthRadiusKm     = 6371.0
+)
+
+// Остановка гастрольного маршрута
+type TourStop struct {
+	Location string    `json:"location"`
+	Name     string    `json:"name"`
+	Country  string    `json:"country"`
+	Date     time.Time `json:"date"`
+	// Расстояние от предыдущей остановки, 0 если координаты неизвестны
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// Статистика гастролей группы
+type TourStats struct {
+	Route            []TourStop `json:"route"`
+	Shows            int        `json:"shows"`
+	TotalDistanceKm  float64    `json:"totalDistanceKm"`
+	Countries        []string   `json:"countries"`
+	BusiestYear      int        `json:"busiestYear,omitempty"`
+	BusiestYearShows int        `json:"busiestYearShows,omitempty"`
+	AverageGapDays   float64    `json:"averageGapDays"`
+	// Даты, которые не удалось разобрать
+	InvalidDates []string `json:"invalidDates,omitempty"`
+}
+
+// Функция разбора даты концерта вида "dd-mm-yyyy" (в API даты иногда начинаются со "*")
+func ParseConcertDate(date string) (time.Time, error) {
+	return time.Parse(concertDateLayout, strings.TrimPrefix(strings.TrimSpace(date), "*"))
+}
+
+// Функция расчета расстояния между точками по формуле гаверсинусов
+func distanceKm(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// Функция восстановления гастрольного маршрута и расчета статистики по связям "локация - даты"
+func ComputeTour(datesLocations map[string][]string) TourStats {
+	stats := TourStats{Route: []TourStop{}, Countries: []string{}}
+
+	for location, dates := range datesLocations {
+		_, country := ParseLocation(location)
+		for _, d := range dates {
+			date, err := ParseConcertDate(d)
+			if err != nil {
+				stats.InvalidDates = append(stats.InvalidDates, location+" "+d)
+				continue
+			}
+			stats.Route = append(stats.Route, TourStop{
+				Location: location,
+				Name:     LocationName(location),
+				Country:  country,
+				Date:     date,
+			})
+		}
+	}
+
+	sort.Slice(stats.Route, func(i, j int) bool {
+		if !stats.Route[i].Date.Equal(stats.Route[j].Date) {
+			return stats.Route[i].Date.Before(stats.Route[j].Date)
+		}
+		return stats.Route[i].Location < stats.Route[j].Location
+	})
+	sort.Strings(stats.InvalidDates)
+
+	stats.Shows = len(stats.Route)
+	if stats.Shows == 0 {
+		return stats
+	}
+
+	showsPerYear := make(map[int]int)
+	for i, stop := range stats.Route {
+		if !repeatString(stats.Countries, stop.Country) {
+			stats.Countries = append(stats.Countries, stop.Country)
+		}
+
+		year := stop.Date.Year()
+		showsPerYear[year]++
+		if showsPerYear[year] > stats.BusiestYearShows ||
+			showsPerYear[year] == stats.BusiestYearShows && year < stats.BusiestYear {
+			stats.BusiestYear, stats.BusiestYearShows = year, showsPerYear[year]
+		}
+
+		if i == 0 {
+			continue
+		}
+		from, okFrom := LookupLocation(stats.Route[i-1].Location)
+		to, okTo := LookupLocation(stop.Location)
+		if okFrom && okTo {
+			d := math.Round(distanceKm(from, to))
+			stats.Route[i].DistanceKm = d
+			stats.TotalDistanceKm += d
+		}
+	}
+	sort.Strings(stats.Countries)
+
+	if stats.Shows > 1 {
+		span := stats.Route[stats.Shows-1].Date.Sub(stats.Route[0].Date)
+		stats.AverageGapDays = math.Round(span.Hours()/24/float64(stats.Shows-1)*10) / 10
+	}
+
+	return stats
+}
+
+// Функция получения читаемых названий посещенных стран
+func (s TourStats) CountryNames() []string {
+	names := make([]string, 0, len(s.Countries))
+	for _, c := range s.Countries {
+		names = append(names, HumanizeKey(c))
+	}
+	return names
+}
+
+// Функция нанесения маршрута на карту гастролей в хронологическом порядке
+func (m *TourMap) SetRoute(route []TourStop) {
+	points := make(map[string]MapPoint, len(m.Points))
+	for _, p := range m.Points {
+		points[p.Location] = p
+	}
+
+	var path []string
+	for _, stop := range route {
+		p, ok := points[stop.Location]
+		if !ok {
+			continue
+		}
+		path = append(path, formatFloat(p.X)+","+formatFloat(p.Y))
+	}
+
+	if len(path) > 1 {
+		m.Route = strings.Join(path, " ")
+	}
+}
@@ -0,0 +1,305 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Экраны терминального интерфейса
+const (
+	browserList = iota
+	browserBand
+	browserCountries
+)
+
+const (
+	browserPerPage = 15
+
+	ansiClear = "\x1b[H\x1b[2J"
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+)
+
+// Состояние терминального интерфейса: текущий экран, фильтры и страница списка
+type browser struct {
+	out  io.Writer
+	ansi bool
+
+	view      int
+	query     string
+	country   string
+	sort      string
+	desc      bool
+	page      int
+	band      Band
+	countries []CountrySummary
+	message   string
+}
+
+// Функция выполнения команды browse: терминальный интерфейс для просмотра групп
+func runBrowse(args []string, stdout io.Writer) error {
+	var opts cliOptions
+	fs := commandFlags("browse", "browse [-offline]", &opts)
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("лишние аргументы: %v", positional)
+	}
+
+	if err := opts.load(); err != nil {
+		return err
+	}
+
+	return RunBrowser(os.Stdin, stdout, isTerminal(stdout))
+}
+
+// Функция проверки, что вывод идет в терминал, а не в файл или канал
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Функция запуска терминального интерфейса над загруженными данными.
+// Команды читаются построчно из in; с ansi экран очищается перед каждой отрисовкой.
+func RunBrowser(in io.Reader, out io.Writer, ansi bool) error {
+	b := &browser{out: out, ansi: ansi, sort: "name", page: 1}
+	scanner := bufio.NewScanner(in)
+
+	for {
+		b.render()
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		if !b.handle(strings.TrimSpace(scanner.Text())) {
+			return nil
+		}
+	}
+}
+
+// Функция получения групп с учетом поиска и фильтра по стране
+func (b *browser) bands() []Band {
+	bands := SnapshotBands()
+
+	if b.query != "" {
+		found, err := SearchRecords(bands, b.query)
+		if err != nil {
+			return nil
+		}
+		bands = *found
+	}
+
+	if b.country == "" {
+		return bands
+	}
+	var filtered []Band
+	for _, band := range bands {
+		for location := range band.Relations {
+			if _, country := ParseLocation(location); country == b.country {
+				filtered = append(filtered, band)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Функция обработки команды, возвращает false для выхода
+func (b *browser) handle(cmd string) bool {
+	b.message = ""
+
+	if cmd == "q" {
+		return false
+	}
+
+	switch b.view {
+	case browserBand:
+		b.view = browserList
+		if cmd != "" && cmd != "b" {
+			return b.handle(cmd)
+		}
+	case browserCountries:
+		if cmd == "" || cmd == "b" {
+			b.view = browserList
+			return true
+		}
+		n, err := strconv.Atoi(cmd)
+		if err != nil || n < 1 || n > len(b.countries) {
+			b.message = "Введите номер страны из списка"
+			return true
+		}
+		b.country = b.countries[n-1].Key
+		b.page = 1
+		b.view = browserList
+	default:
+		b.handleList(cmd)
+	}
+
+	return true
+}
+
+// Функция обработки команды на экране списка групп
+func (b *browser) handleList(cmd string) {
+	name, arg, _ := strings.Cut(cmd, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case cmd == "":
+	case cmd == "n":
+		b.page++
+	case cmd == "p":
+		if b.page > 1 {
+			b.page--
+		}
+	case cmd == "x":
+		b.query, b.country, b.page = "", "", 1
+	case strings.HasPrefix(cmd, "/"):
+		b.query = strings.TrimSpace(cmd[1:])
+		b.page = 1
+	case name == "l" && arg == "":
+		b.countries = BuildPlaces(SnapshotBands())
+		sortCountries(b.countries, "name")
+		b.view = browserCountries
+	case name == "l":
+		key := countryKey(arg)
+		for _, c := range BuildPlaces(SnapshotBands()) {
+			if c.Key == key {
+				b.country = key
+				b.page = 1
+				return
+			}
+		}
+		b.message = fmt.Sprintf("Концертов в стране %q нет", arg)
+	case name == "s":
+		b.desc = strings.HasPrefix(arg, "-")
+		key := strings.TrimPrefix(arg, "-")
+		if !bandSortKeys[key] {
+			b.message = "Сортировка: id, name, creationDate, firstAlbum, members, concerts (с - в начале по убыванию)"
+			return
+		}
+		b.sort = key
+		b.page = 1
+	default:
+		id, err := strconv.Atoi(cmd)
+		if err != nil {
+			b.message = fmt.Sprintf("Неизвестная команда %q", cmd)
+			return
+		}
+		band, ok := bandByID(id)
+		if !ok {
+			b.message = fmt.Sprintf("Группа %d не найдена", id)
+			return
+		}
+		b.band = band
+		b.view = browserBand
+	}
+}
+
+// Функция отрисовки текущего экрана
+func (b *browser) render() {
+	if b.ansi {
+		fmt.Fprint(b.out, ansiClear)
+	}
+
+	switch b.view {
+	case browserBand:
+		b.renderBand()
+	case browserCountries:
+		b.renderCountries()
+	default:
+		b.renderList()
+	}
+
+	if b.message != "" {
+		fmt.Fprintln(b.out, b.message)
+	}
+}
+
+// Функция вывода заголовка экрана
+func (b *browser) title(s string) {
+	if b.ansi {
+		s = ansiBold + s + ansiReset
+	}
+	fmt.Fprintln(b.out, s)
+	fmt.Fprintln(b.out)
+}
+
+func (b *browser) renderList() {
+	page := PaginateBands(b.bands(), ListOptions{Sort: b.sort, Desc: b.desc, Page: b.page, PerPage: browserPerPage})
+	if page.TotalPages > 0 && page.Page > page.TotalPages {
+		b.page = page.TotalPages
+		page = PaginateBands(b.bands(), ListOptions{Sort: b.sort, Desc: b.desc, Page: b.page, PerPage: browserPerPage})
+	}
+
+	filters := []string{fmt.Sprintf("групп: %d", page.Total)}
+	if b.query != "" {
+		filters = append(filters, fmt.Sprintf("поиск: %q", b.query))
+	}
+	if b.country != "" {
+		filters = append(filters, "страна: "+HumanizeKey(b.country))
+	}
+	order := b.sort
+	if b.desc {
+		order += " ↓"
+	}
+	filters = append(filters, "сортировка: "+order)
+	b.title("Groupie Tracker · " + strings.Join(filters, " · "))
+
+	if len(page.Items) == 0 {
+		fmt.Fprintln(b.out, "Группы не найдены")
+	} else {
+		tw := tabwriter.NewWriter(b.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tНАЗВАНИЕ\tСОЗДАНА\tПЕРВЫЙ АЛЬБОМ\tУЧАСТНИКИ\tКОНЦЕРТЫ")
+		for _, band := range page.Items {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%d\t%d\n", band.ID, band.Name, band.CreationDate, band.FirstAlbum, len(band.Members), concertCount(band))
+		}
+		tw.Flush()
+		fmt.Fprintf(b.out, "\nСтраница %d из %d\n", page.Page, page.TotalPages)
+	}
+
+	fmt.Fprintln(b.out, "[ID] группа  [n]/[p] страницы  [/текст] поиск  [l] страны  [l страна] фильтр  [s поле] сортировка  [x] сбросить  [q] выход")
+}
+
+func (b *browser) renderBand() {
+	b.title(fmt.Sprintf("%s (%d)", b.band.Name, b.band.ID))
+
+	concerts := ListConcerts([]Band{b.band}, "")
+	writeBandSummary(b.out, b.band, concerts)
+
+	tour := ComputeTour(b.band.Relations)
+	if tour.Shows > 0 {
+		fmt.Fprintf(b.out, "Страны: %s; расстояние %.0f км\n", strings.Join(tour.CountryNames(), ", "), tour.TotalDistanceKm)
+	}
+	if len(concerts) > 0 {
+		fmt.Fprintln(b.out)
+		writeConcertsTable(b.out, concerts, false)
+	}
+
+	fmt.Fprintln(b.out, "\n[b] назад  [q] выход")
+}
+
+func (b *browser) renderCountries() {
+	b.title("Страны")
+
+	tw := tabwriter.NewWriter(b.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "№\tСТРАНА\tГРУППЫ\tКОНЦЕРТЫ")
+	for i, c := range b.countries {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\n", i+1, c.Name, c.Bands, c.Concerts)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(b.out, "\n[№] показать группы страны  [b] назад  [q] выход")
+}
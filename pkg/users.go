@@ -0,0 +1,421 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultUsersFile  = "users.json"
+	sessionCookieName = "session"
+	sessionLifetime   = 30 * 24 * time.Hour
+	minPasswordLength = 8
+	passwordKeyLength = 32
+	passwordHashAlgo  = "pbkdf2-sha256"
+)
+
+var (
+	// Количество итераций PBKDF2 при хешировании новых паролей
+	PasswordIterations = 120000
+
+	users        []User
+	userSessions []Session
+	usersMu      sync.Mutex
+
+	usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{3,32}$`)
+
+	ErrInvalidUsername    = errors.New("имя пользователя должно содержать от 3 до 32 символов a-z, 0-9, _ . -")
+	ErrWeakPassword       = fmt.Errorf("пароль должен содержать не менее %d символов", minPasswordLength)
+	ErrUserExists         = errors.New("пользователь уже существует")
+	ErrInvalidCredentials = errors.New("неверное имя пользователя или пароль")
+	ErrUserNotFound       = errors.New("пользователь не найден")
+	// Без хранилища пользователи и сессии пропали бы при перезапуске
+	ErrUserStorageUnavailable = errors.New("хранилище пользователей недоступно, регистрация и вход отключены")
+)
+
+// Пользователь сайта
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"passwordHash"`
+	Follows      []int     `json:"follows,omitempty"`
+	Created      time.Time `json:"created"`
+}
+
+// Сессия пользователя, хранится только хеш токена из cookie
+type Session struct {
+	TokenHash string    `json:"tokenHash"`
+	UserID    string    `json:"userId"`
+	Expires   time.Time `json:"expires"`
+}
+
+// Предстоящий концерт отслеживаемой группы
+type UpcomingConcert struct {
+	BandID   int
+	BandName string
+	Location string
+	Date     time.Time
+}
+
+// Функция получения пути к файлу пользователей из переменной окружения USERS_FILE.
+// Файл использовался до появления хранилища и переносится в него миграцией.
+func usersFile() string {
+	if f := os.Getenv("USERS_FILE"); f != "" {
+		return f
+	}
+	return defaultUsersFile
+}
+
+// Функция загрузки пользователей и сессий из хранилища, просроченные сессии удаляются.
+// Без открытого хранилища регистрация и вход не работают.
+func LoadUsers() error {
+	repo := Storage()
+	if repo == nil {
+		return ErrUserStorageUnavailable
+	}
+
+	list, err := repo.Users()
+	if err != nil {
+		return err
+	}
+	sessions, err := repo.Sessions()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	active := sessions[:0]
+	for _, s := range sessions {
+		if s.Expires.After(now) {
+			active = append(active, s)
+			continue
+		}
+		if err := repo.DeleteSession(s.TokenHash); err != nil {
+			return err
+		}
+	}
+
+	usersMu.Lock()
+	users = list
+	userSessions = active
+	usersMu.Unlock()
+
+	return nil
+}
+
+// Функция сохранения пользователя в хранилище, вызывается под usersMu
+func saveUser(u User) error {
+	repo := Storage()
+	if repo == nil {
+		return ErrUserStorageUnavailable
+	}
+	return repo.SaveUser(u)
+}
+
+// Функция вычисления ключа PBKDF2-HMAC-SHA256 (RFC 8018)
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		var counter [4]byte
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// Функция хеширования пароля со случайной солью.
+// Формат: pbkdf2-sha256$<итерации>$<соль>$<хеш>
+func HashPassword(password string) (string, error) {
+	salt, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	key := pbkdf2SHA256([]byte(password), []byte(salt), PasswordIterations, passwordKeyLength)
+	return strings.Join([]string{
+		passwordHashAlgo,
+		strconv.Itoa(PasswordIterations),
+		salt,
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// Функция проверки пароля по сохраненному хешу
+func CheckPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != passwordHashAlgo {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	got := pbkdf2SHA256([]byte(password), []byte(parts[2]), iterations, len(want))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// Функция регистрации нового пользователя
+func RegisterUser(username, password string) (User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return User{}, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength {
+		return User{}, ErrWeakPassword
+	}
+	if Storage() == nil {
+		return User{}, ErrUserStorageUnavailable
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return User{}, err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+	user := User{
+		ID:           id,
+		Username:     username,
+		PasswordHash: hash,
+		Created:      time.Now(),
+	}
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	for _, u := range users {
+		if u.Username == username {
+			return User{}, ErrUserExists
+		}
+	}
+
+	users = append(users, user)
+	if err := saveUser(user); err != nil {
+		users = users[:len(users)-1]
+		return User{}, err
+	}
+
+	log.Println("Зарегистрирован пользователь", user.Username)
+
+	return user, nil
+}
+
+// Функция проверки имени пользователя и пароля
+func AuthenticateUser(username, password string) (User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+
+	usersMu.Lock()
+	var found *User
+	for i := range users {
+		if users[i].Username == username {
+			u := users[i]
+			found = &u
+			break
+		}
+	}
+	usersMu.Unlock()
+
+	if found == nil {
+		// Хешируем пароль и для несуществующих пользователей, чтобы время ответа не выдавало их
+		if _, err := HashPassword(password); err != nil {
+			return User{}, err
+		}
+		return User{}, ErrInvalidCredentials
+	}
+	if !CheckPassword(found.PasswordHash, password) {
+		return User{}, ErrInvalidCredentials
+	}
+
+	return *found, nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Функция создания сессии пользователя, возвращает токен для cookie
+func CreateSession(userID string) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	session := Session{
+		TokenHash: hashSessionToken(token),
+		UserID:    userID,
+		Expires:   time.Now().Add(sessionLifetime),
+	}
+	repo := Storage()
+	if repo == nil {
+		return "", ErrUserStorageUnavailable
+	}
+	if err := repo.SaveSession(session); err != nil {
+		return "", err
+	}
+	userSessions = append(userSessions, session)
+
+	return token, nil
+}
+
+// Функция удаления сессии по токену
+func DeleteSession(token string) error {
+	hash := hashSessionToken(token)
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	for i, s := range userSessions {
+		if s.TokenHash == hash {
+			userSessions = append(userSessions[:i:i], userSessions[i+1:]...)
+			if repo := Storage(); repo != nil {
+				return repo.DeleteSession(hash)
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Функция получения пользователя по токену сессии
+func UserBySession(token string) (User, bool) {
+	hash := hashSessionToken(token)
+	now := time.Now()
+
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	for _, s := range userSessions {
+		if s.TokenHash != hash || !s.Expires.After(now) {
+			continue
+		}
+		for _, u := range users {
+			if u.ID == s.UserID {
+				u.Follows = append([]int{}, u.Follows...)
+				return u, true
+			}
+		}
+	}
+
+	return User{}, false
+}
+
+// Функция получения пользователя текущего запроса
+func CurrentUser(r *http.Request) (User, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return User{}, false
+	}
+	return UserBySession(cookie.Value)
+}
+
+// Функция подписки пользователя на группу или отписки от нее
+func SetFollow(userID string, bandID int, follow bool) error {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	for i, u := range users {
+		if u.ID != userID {
+			continue
+		}
+
+		follows := make([]int, 0, len(u.Follows)+1)
+		for _, id := range u.Follows {
+			if id != bandID {
+				follows = append(follows, id)
+			}
+		}
+		if follow {
+			follows = append(follows, bandID)
+			sort.Ints(follows)
+		}
+
+		u.Follows = follows
+		if err := saveUser(u); err != nil {
+			return err
+		}
+		users[i] = u
+		return nil
+	}
+
+	return ErrUserNotFound
+}
+
+// Метод проверки, отслеживает ли пользователь группу
+func (u User) IsFollowing(bandID int) bool {
+	return repeatInt(u.Follows, bandID)
+}
+
+// Функция получения предстоящих концертов выбранных групп, от ближайших к дальним
+func UpcomingConcerts(bands []Band, bandIDs []int, now time.Time) []UpcomingConcert {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var list []UpcomingConcert
+	for _, b := range bands {
+		if !repeatInt(bandIDs, b.ID) {
+			continue
+		}
+		for _, c := range ConcertsFromRelations(b.Relations) {
+			date, err := ParseConcertDate(c.Date)
+			if err != nil || date.Before(today) {
+				continue
+			}
+			list = append(list, UpcomingConcert{
+				BandID:   b.ID,
+				BandName: b.Name,
+				Location: LocationName(c.Location),
+				Date:     date,
+			})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if !list[i].Date.Equal(list[j].Date) {
+			return list[i].Date.Before(list[j].Date)
+		}
+		return list[i].BandName < list[j].BandName
+	})
+
+	return list
+}
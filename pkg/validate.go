@@ -0,0 +1,287 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	earliestCreationYear = 1900
+)
+
+// Проблема в данных, найденная при проверке
+type Issue struct {
+	Severity string `json:"severity"`
+	Source   string `json:"source"`
+	RecordID int    `json:"recordId"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Группа, исключенная из данных из-за ошибок
+type QuarantinedBand struct {
+	Band   Band     `json:"band"`
+	Issues []string `json:"issues"`
+}
+
+// Отчет о качестве данных
+type QualityReport struct {
+	Time        time.Time         `json:"time"`
+	Source      string            `json:"source"`
+	Bands       int               `json:"bands"`
+	Relations   int               `json:"relations"`
+	Locations   int               `json:"locations"`
+	Errors      int               `json:"errors"`
+	Warnings    int               `json:"warnings"`
+	Issues      []Issue           `json:"issues"`
+	Quarantined []QuarantinedBand `json:"quarantined"`
+}
+
+var (
+	qualityReport   QualityReport
+	qualityReportMu sync.Mutex
+)
+
+// Функция получения последнего отчета о качестве данных
+func GetQualityReport() QualityReport {
+	qualityReportMu.Lock()
+	defer qualityReportMu.Unlock()
+
+	return qualityReport
+}
+
+func setQualityReport(report QualityReport) {
+	qualityReportMu.Lock()
+	qualityReport = report
+	qualityReportMu.Unlock()
+
+	if report.Errors > 0 || report.Warnings > 0 {
+		log.Printf("Проверка данных: ошибок %d, предупреждений %d, исключено групп %d\n",
+			report.Errors, report.Warnings, len(report.Quarantined))
+	}
+}
+
+// Функция проверки снимка данных. Группы с ошибками исключаются из снимка
+// вместе со связями и локациями, предупреждения только попадают в отчет.
+func ValidateSnapshot(s Snapshot) (Snapshot, QualityReport) {
+	report := QualityReport{
+		Time:        s.Time,
+		Source:      s.Source,
+		Bands:       len(s.Bands),
+		Relations:   len(s.Relations.Index),
+		Locations:   len(s.Locations.Index),
+		Issues:      []Issue{},
+		Quarantined: []QuarantinedBand{},
+	}
+
+	add := func(severity, source string, id int, field, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{
+			Severity: severity,
+			Source:   source,
+			RecordID: id,
+			Field:    field,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	currentYear := time.Now().Year()
+	seen := make(map[int]bool)
+	// Ошибки по номеру записи в списке: у записей-дублей и записей без номера
+	// общий ID, а исключать нужно только запись с ошибкой
+	broken := make(map[int][]string)
+	brokenIDs := make(map[int]bool)
+	bands := make(map[int]Band)
+
+	for i, b := range s.Bands {
+		errorsBefore := len(report.Issues)
+
+		if b.ID <= 0 {
+			add(SeverityError, "artists", b.ID, "id", "invalid band id %d", b.ID)
+		} else if seen[b.ID] {
+			add(SeverityError, "artists", b.ID, "id", "duplicate band id %d", b.ID)
+		}
+		seen[b.ID] = true
+
+		if strings.TrimSpace(b.Name) == "" {
+			add(SeverityError, "artists", b.ID, "name", "empty band name")
+		}
+		if b.CreationDate < earliestCreationYear || b.CreationDate > currentYear {
+			add(SeverityError, "artists", b.ID, "creationDate", "impossible creation year %d", b.CreationDate)
+		}
+
+		// Ошибки исключают группу, предупреждения ниже - нет
+		for _, issue := range report.Issues[errorsBefore:] {
+			broken[i] = append(broken[i], issue.Message)
+		}
+
+		if u, err := url.Parse(b.Image); b.Image == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(SeverityWarning, "artists", b.ID, "image", "invalid image URL %q", b.Image)
+		}
+		if len(b.Members) == 0 {
+			add(SeverityWarning, "artists", b.ID, "members", "no members")
+		}
+		if album, err := ParseFirstAlbum(b.FirstAlbum); err != nil {
+			add(SeverityWarning, "artists", b.ID, "firstAlbum", "invalid first album date %q", b.FirstAlbum)
+		} else if album.Year() < b.CreationDate {
+			add(SeverityWarning, "artists", b.ID, "firstAlbum", "first album (%v) released before the band was formed (%d)", b.FirstAlbum, b.CreationDate)
+		} else if album.After(time.Now()) {
+			add(SeverityWarning, "artists", b.ID, "firstAlbum", "first album date is in the future: %v", b.FirstAlbum)
+		}
+
+		if _, ok := broken[i]; ok {
+			brokenIDs[b.ID] = true
+		} else {
+			bands[b.ID] = b
+		}
+	}
+
+	relationIDs := make(map[int]bool)
+	for _, rel := range s.Relations.Index {
+		relationIDs[rel.ID] = true
+		b, ok := bands[rel.ID]
+		if !ok {
+			if !brokenIDs[rel.ID] {
+				add(SeverityWarning, "relations", rel.ID, "id", "relations for an unknown band")
+			}
+			continue
+		}
+		for location, dates := range rel.DatesLocations {
+			if place, _ := ParseLocation(location); place == "" {
+				add(SeverityWarning, "relations", rel.ID, "datesLocations", "location without a country %q", location)
+			}
+			for _, d := range dates {
+				date, err := ParseConcertDate(d)
+				if err != nil {
+					add(SeverityWarning, "relations", rel.ID, "datesLocations", "invalid concert date %q in %v", d, location)
+				} else if date.Year() < b.CreationDate {
+					add(SeverityWarning, "relations", rel.ID, "datesLocations", "concert on %v in %v before the band was formed (%d)", d, location, b.CreationDate)
+				}
+			}
+		}
+	}
+
+	locationIDs := make(map[int]bool)
+	for _, loc := range s.Locations.Index {
+		locationIDs[loc.ID] = true
+		if _, ok := bands[loc.ID]; !ok {
+			if !brokenIDs[loc.ID] {
+				add(SeverityWarning, "locations", loc.ID, "id", "locations for an unknown band")
+			}
+		}
+	}
+
+	for id := range bands {
+		if !relationIDs[id] {
+			add(SeverityWarning, "relations", id, "id", "no relations for the band")
+		}
+		if !locationIDs[id] {
+			add(SeverityWarning, "locations", id, "id", "no locations for the band")
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Severity != report.Issues[j].Severity {
+			return report.Issues[i].Severity == SeverityError
+		}
+		return report.Issues[i].RecordID < report.Issues[j].RecordID
+	})
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	if len(broken) == 0 {
+		return s, report
+	}
+
+	// Исключение групп с ошибками. Связи и локации исключаются, только если
+	// с их номером не осталось ни одной группы, например первой записи у дублей.
+	dropped := func(id int) bool {
+		_, ok := bands[id]
+		return !ok && brokenIDs[id]
+	}
+	valid := s
+	valid.Bands = make([]Band, 0, len(s.Bands))
+	for i, b := range s.Bands {
+		if issues, ok := broken[i]; ok {
+			report.Quarantined = append(report.Quarantined, QuarantinedBand{Band: b, Issues: issues})
+			continue
+		}
+		valid.Bands = append(valid.Bands, b)
+	}
+	valid.Relations.Index = nil
+	for _, rel := range s.Relations.Index {
+		if !dropped(rel.ID) {
+			valid.Relations.Index = append(valid.Relations.Index, rel)
+		}
+	}
+	valid.Locations.Index = nil
+	for _, loc := range s.Locations.Index {
+		if !dropped(loc.ID) {
+			valid.Locations.Index = append(valid.Locations.Index, loc)
+		}
+	}
+
+	return valid, report
+}
+
+func QualityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/quality" {
+		ErrorHandler(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		ErrorHandler(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := GetQualityReport()
+
+	templates, err := parseTemplates(r)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+	err = templates.ExecuteTemplate(w, "quality.html", &report)
+	if err != nil {
+		log.Println(err)
+		ErrorHandler(w, r, http.StatusInternalServerError)
+		return
+	}
+}
+
+func APIQualityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/quality" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := GetQualityReport()
+	if report.Issues == nil {
+		report.Issues = []Issue{}
+	}
+	if report.Quarantined == nil {
+		report.Quarantined = []QuarantinedBand{}
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,430 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWebhooksFile  = "webhooks.json"
+	webhookEvent         = "concerts.changed"
+	webhookMaxAttempts   = 3
+	maxWebhookDeliveries = 100
+)
+
+var (
+	// Пауза перед повторной отправкой, удваивается с каждой попыткой
+	WebhookRetryDelay = 2 * time.Second
+
+	webhooks          []Webhook
+	webhookDeliveries []WebhookDelivery
+	webhooksMu        sync.Mutex
+
+	webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+	ErrWebhookNotFound   = errors.New("подписка не найдена")
+	ErrInvalidWebhookURL = errors.New("некорректный адрес подписки")
+)
+
+// Подписка на изменения концертов
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	BandIDs   []int     `json:"bandIds,omitempty"`
+	Locations []string  `json:"locations,omitempty"`
+	Secret    string    `json:"secret"`
+	Created   time.Time `json:"created"`
+}
+
+// Запись журнала доставки
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhookId"`
+	URL        string    `json:"url"`
+	Time       time.Time `json:"time"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+}
+
+// Содержимое запроса, отправляемого подписчику
+type WebhookPayload struct {
+	ID      string       `json:"id"`
+	Event   string       `json:"event"`
+	Time    time.Time    `json:"time"`
+	Changes []BandChange `json:"changes"`
+}
+
+func init() {
+	OnRefresh(func(diff SnapshotDiff, current Snapshot) {
+		go DeliverWebhooks(diff, current)
+	})
+}
+
+// Функция получения пути к файлу подписок из переменной окружения WEBHOOKS_FILE
+func webhooksFile() string {
+	if f := os.Getenv("WEBHOOKS_FILE"); f != "" {
+		return f
+	}
+	return defaultWebhooksFile
+}
+
+// Функция загрузки подписок из файла
+func LoadWebhooks() error {
+	var list []Webhook
+
+	err := readCacheFile(webhooksFile(), &list)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	webhooksMu.Lock()
+	webhooks = list
+	webhooksMu.Unlock()
+
+	return nil
+}
+
+// Функция сохранения подписок в файл, вызывается под webhooksMu.
+// В файле секреты подписей, поэтому он доступен только владельцу.
+func saveWebhooks() error {
+	data, err := json.Marshal(webhooks)
+	if err != nil {
+		return err
+	}
+	return replaceFile(webhooksFile(), data, 0o600)
+}
+
+// Функция генерации случайного идентификатора из n байт. Ошибку нельзя пропускать:
+// вместо случайных данных получились бы нули, одинаковые для всех токенов и солей.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("ошибка генерации случайных данных: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Функция регистрации новой подписки
+func RegisterWebhook(rawURL string, bandIDs []int, locations []string) (Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, fmt.Errorf("%w: %q", ErrInvalidWebhookURL, rawURL)
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return Webhook{}, err
+	}
+	// Нулевой секрет позволил бы подделать подпись уведомлений
+	secret, err := randomHex(32)
+	if err != nil {
+		return Webhook{}, err
+	}
+	hook := Webhook{
+		ID:        id,
+		URL:       u.String(),
+		BandIDs:   bandIDs,
+		Locations: locations,
+		Secret:    secret,
+		Created:   time.Now(),
+	}
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	previous := webhooks
+	webhooks = append(webhooks[:len(webhooks):len(webhooks)], hook)
+	if err := saveWebhooks(); err != nil {
+		webhooks = previous
+		return Webhook{}, err
+	}
+
+	log.Println("Зарегистрирована подписка", hook.ID, hook.URL)
+
+	return hook, nil
+}
+
+// Функция удаления подписки
+func DeleteWebhook(id string) error {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	for i, hook := range webhooks {
+		if hook.ID == id {
+			previous := webhooks
+			webhooks = append(webhooks[:i:i], webhooks[i+1:]...)
+			if err := saveWebhooks(); err != nil {
+				webhooks = previous
+				return err
+			}
+			return nil
+		}
+	}
+
+	return ErrWebhookNotFound
+}
+
+// Функция получения списка подписок
+func ListWebhooks() []Webhook {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	return append([]Webhook{}, webhooks...)
+}
+
+// Функция получения журнала доставки, от новых записей к старым
+func WebhookDeliveries() []WebhookDelivery {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	list := make([]WebhookDelivery, 0, len(webhookDeliveries))
+	for i := len(webhookDeliveries) - 1; i >= 0; i-- {
+		list = append(list, webhookDeliveries[i])
+	}
+	return list
+}
+
+func recordWebhookDelivery(d WebhookDelivery) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	webhookDeliveries = append(webhookDeliveries, d)
+	if len(webhookDeliveries) > maxWebhookDeliveries {
+		webhookDeliveries = webhookDeliveries[len(webhookDeliveries)-maxWebhookDeliveries:]
+	}
+}
+
+// Функция отбора изменений концертов, подходящих под фильтры подписки
+func (hook Webhook) filter(diff SnapshotDiff, current Snapshot) []BandChange {
+	changes := []BandChange{}
+
+	for _, c := range diff.Changed {
+		c.AddedMembers, c.RemovedMembers = nil, nil
+		changes = append(changes, c)
+	}
+
+	// Все концерты новой группы считаются новыми
+	concerts := concertsByID(current.Relations)
+	for _, b := range diff.AddedBands {
+		changes = append(changes, BandChange{BandRef: b, AddedConcerts: concerts[b.ID]})
+	}
+
+	var result []BandChange
+	for _, c := range changes {
+		if len(hook.BandIDs) > 0 && !repeatInt(hook.BandIDs, c.ID) {
+			continue
+		}
+		c.AddedConcerts = hook.filterConcerts(c.AddedConcerts)
+		c.CancelledConcerts = hook.filterConcerts(c.CancelledConcerts)
+		if len(c.AddedConcerts) > 0 || len(c.CancelledConcerts) > 0 {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+func (hook Webhook) filterConcerts(concerts []Concert) []Concert {
+	if len(hook.Locations) == 0 {
+		return concerts
+	}
+
+	var result []Concert
+	for _, c := range concerts {
+		if containsAny(c.Location, hook.Locations) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Функция проверки, содержит ли строка одну из подстрок без учета регистра
+func containsAny(s string, subs []string) bool {
+	s = strings.ToLower(s)
+	for _, sub := range subs {
+		if strings.Contains(s, strings.ToLower(sub)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция подписи содержимого запроса секретом подписки (HMAC-SHA256)
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Функция рассылки изменений концертов всем подходящим подписчикам
+func DeliverWebhooks(diff SnapshotDiff, current Snapshot) {
+	var wg sync.WaitGroup
+
+	for _, hook := range ListWebhooks() {
+		changes := hook.filter(diff, current)
+		if len(changes) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(hook Webhook, changes []BandChange) {
+			defer wg.Done()
+			id, err := randomHex(8)
+			if err != nil {
+				log.Println("Уведомление для подписки", hook.ID, "не отправлено:", err)
+				return
+			}
+			deliverWebhook(hook, WebhookPayload{
+				ID:      id,
+				Event:   webhookEvent,
+				Time:    current.Time,
+				Changes: changes,
+			})
+		}(hook, changes)
+	}
+
+	wg.Wait()
+}
+
+// Функция отправки одного уведомления с повторными попытками
+func deliverWebhook(hook Webhook, payload WebhookPayload) {
+	delivery := WebhookDelivery{ID: payload.ID, WebhookID: hook.ID, URL: hook.URL, Time: time.Now()}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		recordWebhookDelivery(delivery)
+		return
+	}
+
+	delay := WebhookRetryDelay
+	for delivery.Attempts < webhookMaxAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		delivery.Attempts++
+
+		req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+		if err != nil {
+			delivery.Error = err.Error()
+			break
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Groupie-Event", payload.Event)
+		req.Header.Set("X-Groupie-Delivery", payload.ID)
+		req.Header.Set("X-Groupie-Signature", SignWebhookPayload(hook.Secret, body))
+
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			delivery.Error = err.Error()
+			continue
+		}
+		resp.Body.Close()
+
+		delivery.StatusCode = resp.StatusCode
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = resp.Status
+	}
+
+	if !delivery.Success {
+		log.Println("Не удалось доставить уведомление", delivery.ID, "по адресу", hook.URL, ":", delivery.Error)
+	}
+
+	recordWebhookDelivery(delivery)
+}
+
+// Тело запроса на регистрацию подписки
+type webhookRequest struct {
+	URL       string   `json:"url"`
+	BandIDs   []int    `json:"bandIds"`
+	Locations []string `json:"locations"`
+}
+
+func WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/webhooks" {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, ListWebhooks())
+	case http.MethodPost:
+		var req webhookRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeJSONError(w, r, http.StatusBadRequest)
+			return
+		}
+		hook, err := RegisterWebhook(req.URL, req.BandIDs, req.Locations)
+		if err != nil {
+			log.Println(err)
+			if !errors.Is(err, ErrInvalidWebhookURL) {
+				writeJSONError(w, r, http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": LocalizeError(RequestLocale(r), err)})
+			return
+		}
+		writeJSON(w, http.StatusCreated, hook)
+	default:
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+	}
+}
+
+func WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/webhooks/")
+	if id == "" || strings.Contains(id, "/") {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if id == "deliveries" {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, r, http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, WebhookDeliveries())
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		writeJSONError(w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := DeleteWebhook(id)
+	if errors.Is(err, ErrWebhookNotFound) {
+		writeJSONError(w, r, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		writeJSONError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
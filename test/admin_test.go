@@ -0,0 +1,58 @@
+package pkg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 3 для проверки доступа к административной панели
+func TestAdminAuth(t *testing.T) {
+	handler := pkg.AdminAuth(pkg.AdminCacheHandler)
+
+	// Подтест 3.1 без пароля в окружении панель отключена
+	t.Setenv("ADMIN_PASSWORD", "")
+
+	req := httptest.NewRequest("GET", "/admin/cache", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("Ожидался статус %v, но получен %v", http.StatusNotFound, status)
+	}
+
+	// Подтест 3.2 с неверным паролем
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", "secret")
+
+	req = httptest.NewRequest("GET", "/admin/cache", nil)
+	req.SetBasicAuth("admin", "wrong")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Ожидался статус %v, но получен %v", http.StatusUnauthorized, status)
+	}
+
+	// Подтест 3.3 с верным паролем
+	req = httptest.NewRequest("GET", "/admin/cache?format=json", nil)
+	req.SetBasicAuth("admin", "secret")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Ожидался статус %v, но получен %v", http.StatusOK, status)
+	}
+
+	// Подтест 3.4 откат без предыдущего снимка
+	req = httptest.NewRequest("POST", "/admin/rollback?format=json", nil)
+	req.SetBasicAuth("admin", "secret")
+	rr = httptest.NewRecorder()
+	pkg.AdminAuth(pkg.AdminRollbackHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("Ожидался статус %v, но получен %v", http.StatusConflict, status)
+	}
+}
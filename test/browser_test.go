@@ -0,0 +1,64 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 23 для проверки терминального интерфейса
+func TestRunBrowser(t *testing.T) {
+	saved := pkg.ResponseData.Band
+	defer func() { pkg.ResponseData.Band = saved }()
+	pkg.ResponseData.Band = []pkg.Band{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, Relations: map[string][]string{"london-uk": {"01-01-2020"}, "berlin-germany": {"03-01-2020"}}},
+		{ID: 2, Name: "SOJA", Members: []string{"Jacob Hemphill"}, Relations: map[string][]string{"auckland-new_zealand": {"02-02-2020"}}},
+	}
+
+	// Каждая команда отделяется приглашением "> ", экраны проверяются по отдельности
+	browse := func(commands ...string) []string {
+		var out bytes.Buffer
+		if err := pkg.RunBrowser(strings.NewReader(strings.Join(commands, "\n")+"\n"), &out, false); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(out.String(), "> ")
+	}
+
+	// Подтест 23.1 список групп и карточка группы
+	screens := browse("1", "b", "q")
+	if len(screens) != 4 {
+		t.Fatalf("Ожидалось 3 экрана, получено %d", len(screens)-1)
+	}
+	if !strings.Contains(screens[0], "Queen") || !strings.Contains(screens[0], "SOJA") || !strings.Contains(screens[0], "Страница 1 из 1") {
+		t.Errorf("Неожиданный список групп:\n%s", screens[0])
+	}
+	if !strings.Contains(screens[1], "Freddie Mercury, Brian May") || !strings.Contains(screens[1], "Berlin, Germany") {
+		t.Errorf("Неожиданная карточка группы:\n%s", screens[1])
+	}
+	if strings.Index(screens[1], "01-01-2020") > strings.Index(screens[1], "03-01-2020") {
+		t.Errorf("Концерты должны идти в хронологическом порядке:\n%s", screens[1])
+	}
+
+	// Подтест 23.2 поиск и фильтр по стране из списка стран
+	screens = browse("/jacob", "x", "l", "2", "q")
+	if strings.Contains(screens[1], "Queen") || !strings.Contains(screens[1], "SOJA") {
+		t.Errorf("Поиск должен оставить только SOJA:\n%s", screens[1])
+	}
+	if !strings.Contains(screens[3], "New Zealand") {
+		t.Errorf("Неожиданный список стран:\n%s", screens[3])
+	}
+	if !strings.Contains(screens[4], "страна: New Zealand") || strings.Contains(screens[4], "Queen") {
+		t.Errorf("Фильтр по стране не применен:\n%s", screens[4])
+	}
+
+	// Подтест 23.3 фильтр по названию страны и ошибки ввода
+	screens = browse("l germany", "42", "l mars")
+	if strings.Contains(screens[1], "SOJA") || !strings.Contains(screens[1], "Queen") {
+		t.Errorf("Фильтр по Германии должен оставить только Queen:\n%s", screens[1])
+	}
+	if !strings.Contains(screens[2], "Группа 42 не найдена") || !strings.Contains(screens[3], `Концертов в стране "mars" нет`) {
+		t.Errorf("Ожидались сообщения об ошибках:\n%s\n%s", screens[2], screens[3])
+	}
+}
@@ -0,0 +1,137 @@
+package pkg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Функция получения идентификаторов событий календаря
+func calendarUIDs(ics string) []string {
+	var uids []string
+	for _, m := range regexp.MustCompile(`(?m)^UID:(\S+)\r$`).FindAllStringSubmatch(ics, -1) {
+		uids = append(uids, m[1])
+	}
+	return uids
+}
+
+// Тест 29 для проверки календарей для подписки
+func TestCalendarFeeds(t *testing.T) {
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	serve := func(handler http.HandlerFunc, target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	// Подтест 29.1 идентификаторы концертов не меняются между запросами и совпадают в общем календаре
+	first := serve(pkg.BandCalendarHandler, "/band/1/calendar.ics", nil)
+	if first.Code != http.StatusOK || first.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("Неверный ответ календаря группы: %d %q", first.Code, first.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(first.Body.String(), "REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n") {
+		t.Errorf("В календаре для подписки нет интервала обновления:\n%s", first.Body.String())
+	}
+
+	uids := calendarUIDs(first.Body.String())
+	want := []string{
+		pkg.ConcertUID(1, pkg.Concert{Location: "london-uk", Date: "01-01-2020"}),
+		pkg.ConcertUID(1, pkg.Concert{Location: "berlin-germany", Date: "05-01-2020"}),
+	}
+	for _, uid := range want {
+		if !strings.Contains(strings.Join(uids, " "), uid) {
+			t.Errorf("Ожидался идентификатор %s, получено %v", uid, uids)
+		}
+	}
+	if len(uids) != len(want) {
+		t.Errorf("Ожидалось %d события, получено %v", len(want), uids)
+	}
+
+	second := serve(pkg.BandCalendarHandler, "/band/1/calendar.ics", nil)
+	if second.Body.String() != first.Body.String() || second.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("Повторный запрос вернул другой календарь")
+	}
+
+	combined := serve(pkg.CombinedCalendarHandler, "/calendar.ics?bands=1", nil)
+	if got := calendarUIDs(combined.Body.String()); strings.Join(got, " ") != strings.Join(uids, " ") {
+		t.Errorf("Идентификаторы в общем календаре отличаются: %v и %v", got, uids)
+	}
+
+	// Подтест 29.2 условный запрос с совпадающим ETag получает 304 без тела
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Календарь отправлен без ETag")
+	}
+	rr := serve(pkg.BandCalendarHandler, "/band/1/calendar.ics", http.Header{"If-None-Match": {etag}})
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("Ожидался статус 304 без тела, получено %d и %d байт", rr.Code, rr.Body.Len())
+	}
+	rr = serve(pkg.BandCalendarHandler, "/band/1/calendar.ics", http.Header{"If-None-Match": {`"stale"`}})
+	if rr.Code != http.StatusOK {
+		t.Errorf("Для устаревшего ETag ожидался статус 200, получено %d", rr.Code)
+	}
+	rr = serve(pkg.CombinedCalendarHandler, "/calendar.ics?bands=1,2", http.Header{"If-None-Match": {etag}})
+	if rr.Code != http.StatusOK {
+		t.Errorf("ETag календаря одной группы не должен подходить общему календарю, получено %d", rr.Code)
+	}
+
+	// Подтест 29.3 разбор списка групп: пробелы, повторы, неизвестные и некорректные номера
+	requests := []struct {
+		target string
+		status int
+		events int
+	}{
+		{"/calendar.ics?bands=1,2", http.StatusOK, 3},
+		{"/calendar.ics?bands=2,%201,,2,1", http.StatusOK, 3},
+		{"/calendar.ics?bands=1,999", http.StatusOK, 2},
+		{"/calendar.ics?bands=999", http.StatusNotFound, 0},
+		{"/calendar.ics?bands=1,queen", http.StatusBadRequest, 0},
+		{"/calendar.ics?bands=,,", http.StatusBadRequest, 0},
+		{"/calendar.ics", http.StatusBadRequest, 0},
+		{"/calendar.ics?ids=1", http.StatusBadRequest, 0},
+	}
+	for _, request := range requests {
+		rr := serve(pkg.CombinedCalendarHandler, request.target, nil)
+		if rr.Code != request.status {
+			t.Errorf("%s: ожидался статус %d, получен %d", request.target, request.status, rr.Code)
+			continue
+		}
+		if events := strings.Count(rr.Body.String(), "BEGIN:VEVENT"); request.status == http.StatusOK && events != request.events {
+			t.Errorf("%s: ожидалось %d событий, получено %d", request.target, request.events, events)
+		}
+	}
+
+	// Подтест 29.4 не более 50 разных групп в одном календаре; повторы не считаются
+	ids := make([]string, 0, 51)
+	for id := 1; id <= 51; id++ {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	if rr := serve(pkg.CombinedCalendarHandler, "/calendar.ics?bands="+strings.Join(ids[:50], ","), nil); rr.Code != http.StatusOK {
+		t.Errorf("Для 50 групп ожидался статус 200, получено %d", rr.Code)
+	}
+	if rr := serve(pkg.CombinedCalendarHandler, "/calendar.ics?bands="+strings.Join(ids, ","), nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Для 51 группы ожидался статус 400, получено %d", rr.Code)
+	}
+	if rr := serve(pkg.CombinedCalendarHandler, "/calendar.ics?bands="+strings.Repeat("1,", 60)+"2", nil); rr.Code != http.StatusOK {
+		t.Errorf("Повторы не должны учитываться в пределе групп, получено %d", rr.Code)
+	}
+
+	// Подтест 29.5 неизвестные пути календаря группы
+	for _, target := range []string{"/band/999/calendar.ics", "/band/queen/calendar.ics", "/band/1/calendar.txt", "/band/1"} {
+		if rr := serve(pkg.BandCalendarHandler, target, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("%s: ожидался статус 404, получено %d", target, rr.Code)
+		}
+	}
+}
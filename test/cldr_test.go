@@ -0,0 +1,100 @@
+package pkg_test
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 25 для проверки форматирования дат и названий стран по языку
+func TestLocaleFormatting(t *testing.T) {
+	date := time.Date(2020, time.January, 5, 21, 30, 0, 0, time.UTC)
+
+	// Подтест 25.1 даты по шаблонам CLDR
+	formats := []struct {
+		locale, style, want string
+	}{
+		{"en", pkg.DateLong, "January 5, 2020"},
+		{"en", pkg.DateMedium, "Jan 5, 2020"},
+		{"en", pkg.DateShort, "1/5/20"},
+		{"ru", pkg.DateLong, "5 января 2020 г."},
+		{"ru", pkg.DateMedium, "5 янв. 2020 г."},
+		{"ru", pkg.DateShort, "05.01.2020"},
+		{"de", pkg.DateMedium, "Jan 5, 2020"},
+	}
+	for _, f := range formats {
+		if got := pkg.FormatDate(f.locale, date, f.style); got != f.want {
+			t.Errorf("%s %s: ожидалось %q, получено %q", f.locale, f.style, f.want, got)
+		}
+	}
+	if got := pkg.FormatDateTime("en", date); got != "Jan 5, 2020, 9:30 PM" {
+		t.Errorf("Неожиданные дата и время: %q", got)
+	}
+	if got := pkg.FormatDateTime("ru", date); got != "5 янв. 2020 г., 21:30" {
+		t.Errorf("Неожиданные дата и время: %q", got)
+	}
+
+	// Подтест 25.2 даты из API, в том числе со звездочкой и некорректные
+	if got := pkg.FormatConcertDate("ru", "*23-08-2019", pkg.DateLong); got != "23 августа 2019 г." {
+		t.Errorf("Неожиданная дата концерта: %q", got)
+	}
+	if got := pkg.FormatConcertDate("en", "not a date", pkg.DateLong); got != "not a date" {
+		t.Errorf("Некорректная дата должна выводиться без изменений, получено %q", got)
+	}
+
+	// Подтест 25.3 названия стран и локаций
+	places := []struct {
+		locale, location, want string
+	}{
+		{"en", "los_angeles-usa", "Los Angeles, United States"},
+		{"ru", "los_angeles-usa", "Los Angeles, Соединенные Штаты"},
+		{"ru", "auckland-new_zealand", "Auckland, Новая Зеландия"},
+		{"ru", "germany", "Германия"},
+		{"ru", "springfield-atlantis", "Springfield, Atlantis"},
+	}
+	for _, p := range places {
+		if got := pkg.PlaceName(p.locale, p.location); got != p.want {
+			t.Errorf("%s %s: ожидалось %q, получено %q", p.locale, p.location, p.want, got)
+		}
+	}
+
+	// Подтест 25.4 у всех стран из справочника координат есть перевод
+	file, err := os.Open("pkg/gazetteer.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, _, _ := strings.Cut(scanner.Text(), ",")
+		if strings.HasPrefix(key, "#") || key == "key" {
+			continue
+		}
+		_, country := pkg.ParseLocation(key)
+		if pkg.CountryName("ru", country) == pkg.HumanizeKey(country) {
+			t.Errorf("Нет перевода названия страны %q", country)
+		}
+	}
+
+	// Подтест 25.5 страница группы с датами и странами на языке запроса
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	rr := httptest.NewRecorder()
+	pkg.BandHandler(rr, httptest.NewRequest("GET", "/band?id=1&lang=ru", nil))
+	body := rr.Body.String()
+	for _, want := range []string{"14 дек. 1973 г.", "Berlin, Германия", "5 янв. 2020 г.", "London, Великобритания"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("На странице группы нет %q", want)
+		}
+	}
+	if strings.Contains(body, "berlin-germany") || strings.Contains(body, "05-01-2020") {
+		t.Errorf("На странице группы остались ключи локаций или даты из API")
+	}
+}
@@ -0,0 +1,116 @@
+package pkg_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 22 для проверки команд командной строки
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("USERS_FILE", filepath.Join(dir, "users.json"))
+	t.Setenv("DB_FILE", filepath.Join(dir, "groupie.db"))
+
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+
+	repo, err := pkg.OpenRepository(filepath.Join(dir, "groupie.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.SaveSnapshot(pkg.Snapshot{
+		Bands: []pkg.Band{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+			{ID: 2, Name: "SOJA", Members: []string{"Jacob Hemphill"}, CreationDate: 1997, FirstAlbum: "05-06-2002"},
+		},
+		Relations: relations(map[int]map[string][]string{
+			1: {"london-uk": {"01-01-2020"}, "berlin-germany": {"05-01-2020", "03-01-2020"}},
+			2: {"auckland-new_zealand": {"02-02-2020"}, "dusseldorf-germany": {"04-01-2020"}},
+		}),
+		Time:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Source: pkg.SourceAPI,
+	})
+	repo.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := pkg.RunCommand(args[0], args[1:], &out)
+		return out.String(), err
+	}
+
+	// Подтест 22.1 поиск в виде таблицы, флаги после запроса
+	out, err := run("search", "queen", "-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "1 ") || !strings.Contains(lines[1], "Queen") {
+		t.Errorf("Неожиданная таблица поиска:\n%s", out)
+	}
+
+	// Подтест 22.2 поиск в формате JSON, пустой результат - пустой массив
+	out, err = run("search", "-offline", "-json", "nothing")
+	if err != nil || strings.TrimSpace(out) != "[]" {
+		t.Errorf("Ожидался пустой массив, получено %q %v", out, err)
+	}
+
+	// Подтест 22.3 группа с концертами в хронологическом порядке
+	out, err = run("band", "1", "-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	london := strings.Index(out, "01-01-2020")
+	berlin := strings.Index(out, "03-01-2020")
+	if !strings.Contains(out, "Freddie Mercury, Brian May") || london < 0 || berlin < london {
+		t.Errorf("Неожиданное описание группы:\n%s", out)
+	}
+
+	var band pkg.APIBand
+	out, err = run("band", "-json", "-offline", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &band); err != nil || band.Name != "SOJA" || band.Tour == nil {
+		t.Errorf("Неожиданная группа в JSON: %s %v", out, err)
+	}
+
+	if _, err := run("band", "99", "-offline"); err == nil {
+		t.Errorf("Ожидалась ошибка для несуществующей группы")
+	}
+
+	// Подтест 22.4 концерты в стране по ключу и по названию
+	var concerts []pkg.ConcertListing
+	out, err = run("concerts", "--country=germany", "-json", "-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &concerts); err != nil {
+		t.Fatal(err)
+	}
+	if len(concerts) != 3 || concerts[0].Date != "03-01-2020" || concerts[1].Band != "SOJA" || concerts[2].Place != "Berlin, Germany" {
+		t.Errorf("Неожиданные концерты в Германии: %+v", concerts)
+	}
+
+	out, err = run("concerts", "-country", "New Zealand", "-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Auckland, New Zealand") || strings.Contains(out, "Germany") {
+		t.Errorf("Неожиданные концерты в Новой Зеландии:\n%s", out)
+	}
+
+	// Подтест 22.5 неизвестная команда
+	if _, err := run("unknown"); !errors.Is(err, pkg.ErrUnknownCommand) {
+		t.Errorf("Ожидалась ошибка неизвестной команды, получено %v", err)
+	}
+}
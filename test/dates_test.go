@@ -0,0 +1,60 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 10 для проверки поиска по диапазону дат
+func TestSearchDateRange(t *testing.T) {
+	bands := []pkg.Band{
+		{ID: 1, Name: "Queen", CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "ABBA", CreationDate: 1972, FirstAlbum: "30-03-1973"},
+		{ID: 3, Name: "Pink Floyd", CreationDate: 1965, FirstAlbum: "05-08-1967"},
+		{ID: 4, Name: "Broken", CreationDate: 1990, FirstAlbum: "31-02-1991"},
+	}
+	pkg.ParseFirstAlbums(bands)
+
+	if !bands[3].FirstAlbumDate.IsZero() {
+		t.Errorf("Некорректная дата не должна разбираться: %v", bands[3].FirstAlbumDate)
+	}
+
+	tests := []struct {
+		query string
+		ids   []int
+	}{
+		{"first album between 1970 and 1975", []int{1, 2}},
+		{"First Album: before 1970", []int{3}},
+		{"album after 30-03-1973", []int{1}},
+		{"formed before 1970", []int{3}},
+		{"formed in 1972", []int{2}},
+		{"created from 1975 to 1965", []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		found, err := pkg.SearchRecords(bands, tt.query)
+		if err != nil {
+			t.Errorf("%q: неожиданная ошибка %v", tt.query, err)
+			continue
+		}
+		if len(*found) != len(tt.ids) {
+			t.Errorf("%q: ожидалось %v групп, получено %v", tt.query, len(tt.ids), len(*found))
+			continue
+		}
+		for i, b := range *found {
+			if b.ID != tt.ids[i] {
+				t.Errorf("%q: ожидалась группа %v, получена %v", tt.query, tt.ids[i], b.ID)
+			}
+		}
+	}
+
+	if _, err := pkg.SearchRecords(bands, "formed before 19x0"); err == nil {
+		t.Errorf("Ожидалась ошибка для некорректной даты")
+	}
+
+	// Обычный поиск по-прежнему работает
+	if found, err := pkg.SearchRecords(bands, "Queen"); err != nil || len(*found) != 1 {
+		t.Errorf("Ожидалась одна группа по имени, получено %v, %v", found, err)
+	}
+}
@@ -0,0 +1,58 @@
+package pkg_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 13 для проверки выгрузки данных
+func TestExport(t *testing.T) {
+	bands := []pkg.Band{{
+		ID:        1,
+		Name:      "Queen, the band",
+		Members:   []string{"Freddie Mercury", "Brian May"},
+		Relations: map[string][]string{"london-uk": {"01-01-2020"}, "playa_del_carmen-mexico": {"02-02-2020"}},
+	}}
+
+	var buf bytes.Buffer
+	if err := pkg.Export(&buf, "members.csv", bands); err != nil {
+		t.Fatal(err)
+	}
+	if want := "band_id,band_name,member,member_slug\n1,\"Queen, the band\",Freddie Mercury,freddie-mercury\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("Неверный CSV участников:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := pkg.Export(&buf, "bands.jsonl", bands); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+		t.Errorf("Ожидалась одна строка JSON Lines, получено %v", lines)
+	}
+
+	if err := pkg.Export(&buf, "bands.xml", bands); err == nil {
+		t.Errorf("Ожидалась ошибка для неизвестного формата")
+	}
+
+	buf.Reset()
+	if err := pkg.WriteICS(&buf, "Queen", bands, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	ics := buf.String()
+	if strings.Count(ics, "BEGIN:VEVENT") != 2 || !strings.Contains(ics, "DTSTART;VALUE=DATE:20200101\r\n") {
+		t.Errorf("Неверный календарь:\n%s", ics)
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Строка календаря длиннее 75 байт: %q", line)
+		}
+	}
+	uid := pkg.ConcertUID(1, pkg.Concert{Location: "london-uk", Date: "01-01-2020"})
+	if !strings.Contains(ics, "UID:"+uid) {
+		t.Errorf("Ожидался постоянный идентификатор концерта %v", uid)
+	}
+}
@@ -0,0 +1,90 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 14 для проверки записей ленты изменений
+func TestFeedEntriesFromDiff(t *testing.T) {
+	from := pkg.Snapshot{
+		Bands:     []pkg.Band{{ID: 1, Name: "Queen"}},
+		Relations: relations(map[int]map[string][]string{1: {"london-uk": {"01-01-2020"}}}),
+		Time:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	to := pkg.Snapshot{
+		Bands:     []pkg.Band{{ID: 1, Name: "Queen"}, {ID: 2, Name: "SOJA"}},
+		Relations: relations(map[int]map[string][]string{1: {"london-uk": {"01-01-2020", "05-01-2020"}}}),
+		Time:      time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	entries := pkg.FeedEntriesFromDiff(pkg.DiffSnapshots(from, to))
+	if len(entries) != 2 {
+		t.Fatalf("Ожидалось 2 записи, получено %v", entries)
+	}
+
+	for _, e := range entries {
+		if !e.Updated.Equal(to.Time) {
+			t.Errorf("Неверное время записи %v: %v", e.ID, e.Updated)
+		}
+		if !strings.HasPrefix(e.ID, "tag:") {
+			t.Errorf("Идентификатор записи должен быть tag URI, получено %v", e.ID)
+		}
+	}
+
+	again := pkg.FeedEntriesFromDiff(pkg.DiffSnapshots(from, to))
+	for i := range entries {
+		if entries[i].ID != again[i].ID {
+			t.Errorf("Идентификаторы записей должны быть стабильны: %v и %v", entries[i].ID, again[i].ID)
+		}
+	}
+	if entries[0].ID == entries[1].ID {
+		t.Errorf("Идентификаторы записей должны различаться")
+	}
+}
+
+// Тест 32 для проверки ленты из сохраненных снимков
+func TestBuildFeedEntries(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HISTORY_DIR", dir)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := func(hour int, bands ...pkg.Band) pkg.Snapshot {
+		return pkg.Snapshot{Bands: bands, Time: start.Add(time.Duration(hour) * time.Hour)}
+	}
+	queen, soja := pkg.Band{ID: 1, Name: "Queen"}, pkg.Band{ID: 2, Name: "SOJA"}
+	oldest := writeHistorySnapshot(t, dir, snapshot(0, queen))
+	writeHistorySnapshot(t, dir, snapshot(1, queen, soja))
+
+	// Подтест 32.1 запись о новой группе из двух снимков
+	entries, err := pkg.BuildFeedEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Title != "New band: SOJA" || !entries[0].Updated.Equal(start.Add(time.Hour)) {
+		t.Fatalf("Неверная лента: %+v", entries)
+	}
+
+	// Подтест 32.2 пока список снимков не изменился, снимки не читаются заново
+	if err := os.WriteFile(filepath.Join(dir, "snapshot-"+oldest+".json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := pkg.BuildFeedEntries(); err != nil || len(again) != 1 || again[0].ID != entries[0].ID {
+		t.Errorf("Ожидалась та же лента, получено %+v, %v", again, err)
+	}
+
+	// Подтест 32.3 новый снимок перестраивает ленту; непрочитанный снимок пропускается
+	writeHistorySnapshot(t, dir, snapshot(2, queen, soja, pkg.Band{ID: 3, Name: "Pink Floyd"}))
+	entries, err = pkg.BuildFeedEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Title != "New band: Pink Floyd" {
+		t.Errorf("Неверная лента после нового снимка: %+v", entries)
+	}
+}
@@ -0,0 +1,41 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 6 для проверки разбора локаций и офлайн-справочника координат
+func TestGazetteerGeocoder(t *testing.T) {
+	place, country := pkg.ParseLocation("north_carolina-usa")
+	if place != "north_carolina" || country != "usa" {
+		t.Errorf("Неверный разбор локации: %q, %q", place, country)
+	}
+	if name := pkg.LocationName("playa_del_carmen-mexico"); name != "Playa Del Carmen, Mexico" {
+		t.Errorf("Неверное название локации: %q", name)
+	}
+
+	g := pkg.GazetteerGeocoder{}
+
+	p, err := g.Geocode(context.Background(), "london-uk")
+	if err != nil || p.Approximate || p.Lat < 51 || p.Lat > 52 {
+		t.Errorf("Неверные координаты London: %+v, %v", p, err)
+	}
+
+	// Неизвестный город в известной стране - координаты центра страны
+	p, err = g.Geocode(context.Background(), "unknown_town-germany")
+	if err != nil || !p.Approximate {
+		t.Errorf("Ожидались приблизительные координаты: %+v, %v", p, err)
+	}
+
+	if _, err = g.Geocode(context.Background(), "nowhere-atlantis"); err != pkg.ErrLocationNotFound {
+		t.Errorf("Ожидалась ошибка %v, получено %v", pkg.ErrLocationNotFound, err)
+	}
+
+	m := pkg.BuildTourMap(map[string][]string{"london-uk": {"01-01-2020"}, "nowhere-atlantis": {"02-01-2020"}})
+	if len(m.Points) != 1 || len(m.Unknown) != 1 {
+		t.Errorf("Неверная карта гастролей: %+v", m)
+	}
+}
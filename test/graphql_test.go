@@ -0,0 +1,78 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 19 для проверки запросов GraphQL
+func TestGraphQL(t *testing.T) {
+	saved := pkg.ResponseData.Band
+	defer func() { pkg.ResponseData.Band = saved }()
+	pkg.ResponseData.Band = []pkg.Band{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, Relations: map[string][]string{"london-uk": {"01-01-2020"}}},
+		{ID: 2, Name: "SOJA", Members: []string{"Brian May"}, Relations: map[string][]string{"berlin-germany": {"02-02-2020"}}},
+	}
+
+	query := func(body string) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		pkg.GraphQLHandler(rec, req)
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"query": `query Band($id: Int!) {
+			band(id: $id) {
+				name
+				people: members { name bands { name } }
+				...Concerts
+			}
+		}
+		fragment Concerts on Band { concerts { date country { name } } }`,
+		"variables": map[string]interface{}{"id": 1},
+	})
+	code, got := query(string(body))
+	want := `{"data":{"band":{"name":"Queen","people":[{"name":"Freddie Mercury","bands":[{"name":"Queen"}]},{"name":"Brian May","bands":[{"name":"Queen"},{"name":"SOJA"}]}],"concerts":[{"date":"01-01-2020","country":{"name":"UK"}}]}}}`
+	if code != http.StatusOK || got != want {
+		t.Errorf("Неверный ответ GraphQL %v:\n%s\nожидалось:\n%s", code, got, want)
+	}
+
+	errorCases := map[string]string{
+		`{"query":"{ band(id: 1) { nam } }"}`:                                                    "has no field",
+		`{"query":"{ band { name } }"}`:                                                          "missing required argument",
+		`{"query":"{ band(id: 1) { name "}`:                                                      "syntax error",
+		`{"query":"mutation { band(id: 1) { name } }"}`:                                          "not supported",
+		`{"query":"{ bands(first: 100) { members { bands { members { bands { name } } } } } }"}`: "complexity",
+	}
+	for body, msg := range errorCases {
+		code, got := query(body)
+		if code != http.StatusBadRequest || !strings.Contains(got, msg) {
+			t.Errorf("Для %s ожидалась ошибка %q, получено %v %s", body, msg, code, got)
+		}
+	}
+
+	// Каждый фрагмент подставляет следующий дважды: запрос меньше 1 КБ
+	// раскрывается в 2^22 полей и должен отклоняться до их перебора
+	var bomb strings.Builder
+	bomb.WriteString("query{...F0}")
+	for i := 0; i < 22; i++ {
+		fmt.Fprintf(&bomb, " fragment F%d on Query{...F%d ...F%d}", i, i+1, i+1)
+	}
+	bomb.WriteString(" fragment F22 on Query{__typename}")
+	start := time.Now()
+	_, errs := pkg.ExecuteGraphQL(bomb.String(), "", nil)
+	if len(errs) == 0 || !strings.Contains(errs[0].Message, "selections") {
+		t.Errorf("Ожидалась ошибка числа полей для вложенных фрагментов, получено %v", errs)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Запрос с вложенными фрагментами выполнялся %v", elapsed)
+	}
+}
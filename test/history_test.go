@@ -0,0 +1,164 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+func relations(m map[int]map[string][]string) pkg.Relations {
+	var r pkg.Relations
+	for id, dl := range m {
+		r.Index = append(r.Index, struct {
+			ID             int                 `json:"id"`
+			DatesLocations map[string][]string `json:"datesLocations"`
+		}{id, dl})
+	}
+	return r
+}
+
+// Тест 4 для проверки сравнения снимков данных
+func TestDiffSnapshots(t *testing.T) {
+	from := pkg.Snapshot{
+		Bands: []pkg.Band{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}},
+			{ID: 2, Name: "SOJA", Members: []string{"Jacob Hemphill"}},
+		},
+		Relations: relations(map[int]map[string][]string{
+			1: {"london-uk": {"01-01-2020"}, "paris-france": {"02-01-2020"}},
+		}),
+	}
+	to := pkg.Snapshot{
+		Bands: []pkg.Band{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Roger Taylor"}},
+			{ID: 3, Name: "Pink Floyd"},
+		},
+		Relations: relations(map[int]map[string][]string{
+			1: {"london-uk": {"01-01-2020", "05-01-2020"}},
+		}),
+	}
+
+	diff := pkg.DiffSnapshots(from, to)
+
+	if len(diff.AddedBands) != 1 || diff.AddedBands[0].ID != 3 {
+		t.Errorf("Ожидалась новая группа 3, получено %v", diff.AddedBands)
+	}
+	if len(diff.RemovedBands) != 1 || diff.RemovedBands[0].ID != 2 {
+		t.Errorf("Ожидалась удаленная группа 2, получено %v", diff.RemovedBands)
+	}
+	if len(diff.Changed) != 1 {
+		t.Fatalf("Ожидалось изменение одной группы, получено %v", diff.Changed)
+	}
+
+	c := diff.Changed[0]
+	if len(c.AddedMembers) != 1 || c.AddedMembers[0] != "Roger Taylor" {
+		t.Errorf("Ожидался новый участник Roger Taylor, получено %v", c.AddedMembers)
+	}
+	if len(c.RemovedMembers) != 1 || c.RemovedMembers[0] != "Brian May" {
+		t.Errorf("Ожидался удаленный участник Brian May, получено %v", c.RemovedMembers)
+	}
+	if len(c.AddedConcerts) != 1 || c.AddedConcerts[0] != (pkg.Concert{Location: "london-uk", Date: "05-01-2020"}) {
+		t.Errorf("Ожидался новый концерт, получено %v", c.AddedConcerts)
+	}
+	if len(c.CancelledConcerts) != 1 || c.CancelledConcerts[0].Location != "paris-france" {
+		t.Errorf("Ожидался отмененный концерт, получено %v", c.CancelledConcerts)
+	}
+
+	if !pkg.DiffSnapshots(to, to).Empty() {
+		t.Errorf("Сравнение снимка с самим собой должно быть пустым")
+	}
+}
+
+// Функция записи снимка в каталог истории так, как его сохраняет обновление данных
+func writeHistorySnapshot(t *testing.T, dir string, s pkg.Snapshot) string {
+	t.Helper()
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatInt(s.Time.UnixNano(), 10)
+	if err := os.WriteFile(filepath.Join(dir, "snapshot-"+id+".json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// Тест 31 для проверки оглавления истории снимков
+func TestSnapshotIndex(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HISTORY_DIR", dir)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i, bands := range [][]pkg.Band{
+		{{ID: 1, Name: "Queen"}},
+		{{ID: 1, Name: "Queen"}, {ID: 2, Name: "SOJA"}},
+		{{ID: 1, Name: "Queen"}, {ID: 2, Name: "SOJA"}, {ID: 3, Name: "Pink Floyd"}},
+	} {
+		s := pkg.Snapshot{Bands: bands, Time: start.Add(time.Duration(i) * time.Hour), Source: pkg.SourceAPI}
+		ids = append(ids, writeHistorySnapshot(t, dir, s))
+	}
+
+	// Подтест 31.1 снимки без оглавления читаются один раз, и оглавление собирается по ним
+	list, err := pkg.ListSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].ID != ids[2] || list[0].Bands != 3 || list[2].ID != ids[0] || list[0].Source != pkg.SourceAPI {
+		t.Fatalf("Неверный список снимков: %+v", list)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.json")); err != nil {
+		t.Fatalf("Оглавление снимков не создано: %v", err)
+	}
+
+	// Подтест 31.2 список строится по оглавлению, а не по содержимому снимков
+	if err := os.WriteFile(filepath.Join(dir, "snapshot-"+ids[0]+".json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	again, err := pkg.ListSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 3 || again[2] != list[2] {
+		t.Errorf("Список снимков должен браться из оглавления, получено %+v", again)
+	}
+	diff, err := pkg.DiffStoredSnapshots("", "")
+	if err != nil || len(diff.AddedBands) != 1 || diff.AddedBands[0].ID != 3 {
+		t.Errorf("Неверное сравнение двух последних снимков: %+v, %v", diff, err)
+	}
+
+	// Подтест 31.3 удаленные снимки пропадают из оглавления, новые добавляются
+	if err := os.Remove(filepath.Join(dir, "snapshot-"+ids[0]+".json")); err != nil {
+		t.Fatal(err)
+	}
+	id := writeHistorySnapshot(t, dir, pkg.Snapshot{Time: start.Add(5 * time.Hour), Source: pkg.SourceFile})
+	if list, err = pkg.ListSnapshots(); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].ID != id || list[0].Source != pkg.SourceFile || list[2].ID != ids[1] {
+		t.Errorf("Неверный список после удаления и добавления снимков: %+v", list)
+	}
+
+	var index []pkg.SnapshotInfo
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err == nil {
+		err = json.Unmarshal(data, &index)
+	}
+	if err != nil || len(index) != 3 {
+		t.Errorf("Оглавление не обновлено: %s, %v", data, err)
+	}
+
+	// Подтест 31.4 поврежденное оглавление собирается заново
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if list, err = pkg.ListSnapshots(); err != nil || len(list) != 3 {
+		t.Errorf("Ожидалось 3 снимка после восстановления оглавления, получено %+v, %v", list, err)
+	}
+}
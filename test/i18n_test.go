@@ -0,0 +1,159 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 24 для проверки переводов страниц и ошибок API
+func TestI18n(t *testing.T) {
+	// Подтест 24.1 каталоги содержат одинаковые ключи
+	want := strings.Join(pkg.CatalogKeys(pkg.DefaultLocale), "\n")
+	for _, locale := range pkg.SupportedLocales() {
+		if got := strings.Join(pkg.CatalogKeys(locale), "\n"); got != want {
+			t.Errorf("Ключи каталога %s отличаются от каталога %s", locale, pkg.DefaultLocale)
+		}
+	}
+
+	// Подтест 24.2 все ключи из шаблонов есть в каталоге
+	known := map[string]bool{}
+	for _, key := range pkg.CatalogKeys(pkg.DefaultLocale) {
+		known[key] = true
+	}
+	files, err := filepath.Glob("web/templates/*.html")
+	if err != nil || len(files) == 0 {
+		t.Fatal("Шаблоны не найдены", err)
+	}
+	keyRe := regexp.MustCompile(`\bT "([^"]+)"`)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range keyRe.FindAllStringSubmatch(string(data), -1) {
+			if !known[m[1]] {
+				t.Errorf("%s: ключа %q нет в каталоге", file, m[1])
+			}
+		}
+	}
+
+	// Подтест 24.3 выбор языка по заголовку Accept-Language
+	negotiation := map[string]string{
+		"":                        "en",
+		"ru-RU,ru;q=0.9,en;q=0.8": "ru",
+		"de-DE,en;q=0.5,ru;q=0.7": "ru",
+		"fr;q=0.9,en-GB;q=0.8":    "en",
+		"ru;q=0,en;q=0.1":         "en",
+		"*":                       "en",
+		"RU":                      "ru",
+	}
+	for header, want := range negotiation {
+		if got := pkg.NegotiateLocale(header); got != want {
+			t.Errorf("Для %q ожидался язык %s, получен %s", header, want, got)
+		}
+	}
+
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", pkg.HomeHandler)
+	mux.HandleFunc("/search", pkg.SearchHandler)
+	mux.HandleFunc("/api/band", pkg.APIBandHandler)
+	mux.HandleFunc("/api/bands", pkg.APIBandsHandler)
+	mux.HandleFunc("/api/search", pkg.APISearchHandler)
+	handler := pkg.WithLocale(mux)
+
+	serve := func(method, target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Подтест 24.4 по умолчанию страницы на английском
+	rr := serve("GET", "/", nil)
+	if body := rr.Body.String(); !strings.Contains(body, `<html lang="en">`) || !strings.Contains(body, "Sort by") {
+		t.Errorf("Ожидалась английская главная страница")
+	}
+
+	// Подтест 24.5 параметр ?lang= переключает язык и запоминается в cookie
+	rr = serve("GET", "/?lang=ru", nil)
+	body := rr.Body.String()
+	if !strings.Contains(body, `<html lang="ru">`) || !strings.Contains(body, "Сортировать по") || rr.Header().Get("Content-Language") != "ru" {
+		t.Errorf("Ожидалась русская главная страница, заголовок Content-Language %q", rr.Header().Get("Content-Language"))
+	}
+	if !strings.Contains(body, `href="/?lang=en"`) {
+		t.Errorf("На странице нет ссылки на английскую версию")
+	}
+	cookie := rr.Header().Get("Set-Cookie")
+	if !strings.HasPrefix(cookie, "lang=ru") {
+		t.Fatalf("Ожидалась cookie с языком, получено %q", cookie)
+	}
+
+	rr = serve("GET", "/", map[string]string{"Cookie": "lang=ru", "Accept-Language": "en"})
+	if !strings.Contains(rr.Body.String(), `<html lang="ru">`) {
+		t.Errorf("Язык из cookie должен иметь приоритет над Accept-Language")
+	}
+
+	// Подтест 24.6 страницы ошибок на языке из Accept-Language
+	rr = serve("POST", "/", map[string]string{"Accept-Language": "ru-RU,ru;q=0.9"})
+	if rr.Code != http.StatusMethodNotAllowed || !strings.Contains(rr.Body.String(), "Метод не поддерживается") {
+		t.Errorf("Ожидалась русская страница ошибки 405, получен статус %d", rr.Code)
+	}
+
+	rr = serve("GET", "/search?query=nothing&lang=ru", nil)
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "Ничего не найдено: nothing") {
+		t.Errorf("Ожидалась русская страница пустого поиска, получен статус %d", rr.Code)
+	}
+
+	rr = serve("GET", "/search?query=nothing", nil)
+	if !strings.Contains(rr.Body.String(), "Nothing found: nothing") {
+		t.Errorf("Ожидалась английская страница пустого поиска")
+	}
+
+	rr = serve("GET", "/search?query=formed+before+1x70", nil)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "Invalid date") {
+		t.Errorf("Для некорректной даты ожидалась страница ошибки 400, получен статус %d", rr.Code)
+	}
+
+	// Подтест 24.7 ошибки API на языке запроса
+	apiError := func(rr *httptest.ResponseRecorder) string {
+		var resp struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Error
+	}
+
+	if msg := apiError(serve("GET", "/api/band?id=99", nil)); msg != "Not Found" {
+		t.Errorf("Ожидалась ошибка \"Not Found\", получено %q", msg)
+	}
+	if msg := apiError(serve("GET", "/api/band?id=99", map[string]string{"Accept-Language": "ru"})); msg != "Не найдено" {
+		t.Errorf("Ожидалась ошибка \"Не найдено\", получено %q", msg)
+	}
+	if msg := apiError(serve("GET", "/api/bands?sort=age&lang=ru", nil)); msg != `Некорректные параметры сортировки или страницы: sort="age"` {
+		t.Errorf("Неожиданная ошибка параметров списка: %q", msg)
+	}
+	if msg := apiError(serve("GET", "/api/bands?sort=age", nil)); msg != `Invalid sort or page parameters: sort="age"` {
+		t.Errorf("Неожиданная ошибка параметров списка: %q", msg)
+	}
+	rr = serve("GET", "/api/search?query=formed+before+1x70&lang=ru", nil)
+	if msg := apiError(rr); rr.Code != http.StatusBadRequest || msg != `Некорректная дата: "1x70"` {
+		t.Errorf("Ожидалась ошибка даты со статусом 400, получено %d %q", rr.Code, msg)
+	}
+}
@@ -0,0 +1,134 @@
+package pkg_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 12 для проверки прокси изображений
+func TestImageHandler(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("IMAGE_CACHE_DIR", cacheDir)
+
+	src := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 300; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	// Маленький файл, в заголовке которого указаны огромные размеры
+	huge := append([]byte(nil), buf.Bytes()...)
+	binary.BigEndian.PutUint32(huge[16:], 100000)
+	binary.BigEndian.PutUint32(huge[20:], 100000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+
+	downloads, failures := 0, 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fake.png":
+			failures++
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<html>not an image</html>"))
+			return
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(huge)
+			return
+		}
+		downloads++
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}))
+	defer upstream.Close()
+
+	saved := pkg.ResponseData.Band
+	defer func() { pkg.ResponseData.Band = saved }()
+	pkg.ResponseData.Band = []pkg.Band{
+		{ID: 1, Name: "Queen", Image: upstream.URL + "/queen.png"},
+		{ID: 2, Name: "Fake", Image: upstream.URL + "/fake.png"},
+		{ID: 3, Name: "Huge", Image: upstream.URL + "/huge.png"},
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		pkg.ImageHandler(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	// Подтест 12.1 уменьшенная копия
+	rr := get("/img/1/thumb")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("Ожидалась уменьшенная копия, получено %v %v", rr.Code, rr.Header())
+	}
+	thumb, _, err := image.Decode(rr.Body)
+	if err != nil || thumb.Bounds().Dx() != 225 || thumb.Bounds().Dy() != 225 {
+		t.Errorf("Неверная уменьшенная копия: %v, %v", thumb.Bounds(), err)
+	}
+
+	// Подтест 12.2 оригинал берется из кэша без повторной загрузки
+	rr = get("/img/1")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/png" || rr.Header().Get("Cache-Control") == "" {
+		t.Errorf("Ожидалось изображение из кэша, получено %v %v", rr.Code, rr.Header())
+	}
+	if downloads != 1 {
+		t.Errorf("Ожидалась одна загрузка, получено %v", downloads)
+	}
+
+	// Подтест 12.3 в кэше только готовые файлы: временные файлы записи переименованы
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Ожидались оригинал и уменьшенная копия, получено %v", entries)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), ".png") && !strings.HasSuffix(e.Name(), "-thumb.jpg") {
+			t.Errorf("Лишний файл в кэше: %s", e.Name())
+		}
+		if info, err := e.Info(); err != nil || info.Mode().Perm() != 0o644 {
+			t.Errorf("Неверные права файла %s в кэше: %v", e.Name(), err)
+		}
+	}
+
+	// Подтест 12.4 содержимое не соответствует типу - заглушка, источник повторно не запрашивается
+	for i := 0; i < 3; i++ {
+		rr = get("/img/2")
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/svg+xml" {
+			t.Errorf("Ожидалась заглушка, получено %v %v", rr.Code, rr.Header())
+		}
+	}
+	if failures != 1 {
+		t.Errorf("Неудачная загрузка должна запоминаться, источник запрошен %v раз", failures)
+	}
+
+	// Подтест 12.5 слишком большие размеры изображения - заглушка без полного декодирования
+	rr = get("/img/3/thumb")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("Ожидалась заглушка для огромного изображения, получено %v %v", rr.Code, rr.Header())
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 2 {
+		t.Errorf("Огромное изображение не должно попасть в кэш: %v", entries)
+	}
+
+	// Подтест 12.6 неизвестная группа
+	if rr = get("/img/99"); rr.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус %v, но получен %v", http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,27 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 8 для проверки объединения участников разных групп
+func TestBuildMembers(t *testing.T) {
+	if slug := pkg.Slugify("  Freddie  Mercury (vocals) "); slug != "freddie-mercury-vocals" {
+		t.Errorf("Неверный slug: %q", slug)
+	}
+
+	members := pkg.BuildMembers([]pkg.Band{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}},
+		{ID: 2, Name: "Brian May Band", Members: []string{"Brian  May"}},
+	})
+
+	m, ok := members["brian-may"]
+	if !ok || len(m.Bands) != 2 {
+		t.Errorf("Ожидался участник двух групп, получено %+v", m)
+	}
+	if len(members) != 2 {
+		t.Errorf("Ожидалось 2 участника, получено %v", len(members))
+	}
+}
@@ -0,0 +1,498 @@
+package pkg_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/client"
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Маршруты из описания API и обработчики, которые их обслуживают
+var openAPIRoutes = []struct {
+	specPath string
+	pattern  string
+	handler  http.HandlerFunc
+}{
+	{"/api/bands", "/api/bands", pkg.APIBandsHandler},
+	{"/api/search", "/api/search", pkg.APISearchHandler},
+	{"/api/suggestions", "/api/suggestions", pkg.APISuggestionsHandler},
+	{"/api/band", "/api/band", pkg.APIBandHandler},
+	{"/api/history", "/api/history", pkg.APIHistoryHandler},
+	{"/api/history/diff", "/api/history/diff", pkg.APIHistoryDiffHandler},
+	{"/api/quality", "/api/quality", pkg.APIQualityHandler},
+	{"/api/webhooks", "/api/webhooks", pkg.AdminAuth(pkg.WebhooksHandler)},
+	{"/api/webhooks/deliveries", "/api/webhooks/", pkg.AdminAuth(pkg.WebhookHandler)},
+	{"/api/webhooks/{id}", "/api/webhooks/", pkg.AdminAuth(pkg.WebhookHandler)},
+	{"/graphql", "/graphql", pkg.GraphQLHandler},
+	{"/admin/cache", "/admin/cache", pkg.AdminAuth(pkg.AdminCacheHandler)},
+	{"/admin/refresh", "/admin/refresh", pkg.AdminAuth(pkg.AdminRefreshHandler)},
+	{"/admin/rollback", "/admin/rollback", pkg.AdminAuth(pkg.AdminRollbackHandler)},
+	{"/band/{id}/calendar.ics", "/band/", pkg.BandCalendarHandler},
+	{"/calendar.ics", "/calendar.ics", pkg.CombinedCalendarHandler},
+	{"/feed.atom", "/feed.atom", pkg.AtomFeedHandler},
+	{"/feed.rss", "/feed.rss", pkg.RSSFeedHandler},
+	{"/export/{file}", "/export/", pkg.ExportHandler},
+	{"/img/{id}", "/img/", pkg.ImageHandler},
+	{"/img/{id}/thumb", "/img/", pkg.ImageHandler},
+	{"/events", "/events", pkg.EventsHandler},
+	{"/openapi.json", "/openapi.json", pkg.OpenAPIHandler},
+}
+
+// Операции с ответом JSON, которые тест не вызывает, и причина
+var openAPISkipped = map[string]string{
+	"POST /admin/refresh": "обращается к внешнему API",
+}
+
+// Описание API, разобранное для проверки ответов
+type openAPISpec struct {
+	doc map[string]interface{}
+}
+
+func loadOpenAPISpec(t *testing.T) openAPISpec {
+	t.Helper()
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(pkg.OpenAPIDocument(), &doc); err != nil {
+		t.Fatalf("Описание API не является корректным JSON: %v", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Fatalf("Ожидалась версия OpenAPI 3.0.3, получено %v", doc["openapi"])
+	}
+	return openAPISpec{doc: doc}
+}
+
+func (s openAPISpec) paths() map[string]interface{} {
+	return s.doc["paths"].(map[string]interface{})
+}
+
+// Функция получения объекта по ссылке вида #/components/schemas/Band
+func (s openAPISpec) resolve(v map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := v["$ref"].(string)
+		if !ok {
+			return v
+		}
+		var node interface{} = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			node = node.(map[string]interface{})[part]
+		}
+		v = node.(map[string]interface{})
+	}
+}
+
+// Функция получения схемы ответа JSON операции для статуса, ok=false если статус не описан
+func (s openAPISpec) responseSchema(path, method string, status int) (map[string]interface{}, bool) {
+	op, _ := s.paths()[path].(map[string]interface{})[strings.ToLower(method)].(map[string]interface{})
+	if op == nil {
+		// Методы, которых нет в описании, отклоняются с ошибкой в формате JSON
+		if status == http.StatusMethodNotAllowed {
+			return map[string]interface{}{"$ref": "#/components/schemas/Error"}, true
+		}
+		return nil, false
+	}
+	resp, ok := op["responses"].(map[string]interface{})[strconv.Itoa(status)].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	resp = s.resolve(resp)
+	content, _ := resp["content"].(map[string]interface{})
+	media, _ := content["application/json"].(map[string]interface{})
+	if media == nil {
+		return nil, true
+	}
+	return media["schema"].(map[string]interface{}), true
+}
+
+// Функция проверки значения по схеме, возвращает список несоответствий
+func (s openAPISpec) validate(schema map[string]interface{}, v interface{}, at string) []string {
+	schema = s.resolve(schema)
+
+	if v == nil {
+		if schema["nullable"] == true || len(schema) == 0 {
+			return nil
+		}
+		return []string{at + ": null"}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == v {
+				found = true
+			}
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: значение %v не входит в %v", at, v, enum)}
+		}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{at + ": ожидался объект"}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: нет обязательного поля %s", at, name))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := props[k].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] != true {
+					problems = append(problems, fmt.Sprintf("%s: поле %s не описано", at, k))
+				}
+				continue
+			}
+			problems = append(problems, s.validate(prop, obj[k], at+"."+k)...)
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return []string{at + ": ожидался массив"}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range list {
+			problems = append(problems, s.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return []string{at + ": ожидалась строка"}
+		}
+		layout := map[string]string{"date-time": time.RFC3339, "date": "2006-01-02"}[fmt.Sprint(schema["format"])]
+		if layout != "" {
+			if _, err := time.Parse(layout, str); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q не в формате %v", at, str, schema["format"]))
+			}
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []string{at + ": ожидалось целое число"}
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return []string{at + ": ожидалось число"}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{at + ": ожидалось логическое значение"}
+		}
+	}
+	return problems
+}
+
+// Тестовые данные для проверки API
+func openAPIFixture() []pkg.Band {
+	bands := []pkg.Band{
+		{
+			ID: 1, Name: "Queen", Image: "https://example.com/queen.jpeg",
+			Members:      []string{"Freddie Mercury", "Brian May"},
+			CreationDate: 1970, FirstAlbum: "14-12-1973",
+			Locations: []string{"london-uk", "berlin-germany"},
+			Relations: map[string][]string{"london-uk": {"01-01-2020"}, "berlin-germany": {"05-01-2020"}},
+		},
+		{
+			ID: 2, Name: "SOJA", Image: "https://example.com/soja.jpeg",
+			Members:      []string{"Jacob Hemphill"},
+			CreationDate: 1997, FirstAlbum: "05-06-2002",
+			Locations: []string{"paris-france"},
+			Relations: map[string][]string{"paris-france": {"02-02-2020"}},
+		},
+	}
+	pkg.ParseFirstAlbums(bands)
+	return bands
+}
+
+// Тест 20 для проверки соответствия обработчиков описанию OpenAPI
+func TestOpenAPIContract(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	t.Setenv("WEBHOOKS_FILE", filepath.Join(t.TempDir(), "webhooks.json"))
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", "secret")
+
+	mux := http.NewServeMux()
+	registered := map[string]bool{}
+	for _, route := range openAPIRoutes {
+		if !registered[route.pattern] {
+			mux.HandleFunc(route.pattern, route.handler)
+			registered[route.pattern] = true
+		}
+	}
+
+	// Подтест 20.1 у каждого пути из описания есть обработчик
+	routed := map[string]bool{}
+	for _, route := range openAPIRoutes {
+		routed[route.specPath] = true
+	}
+	for path := range spec.paths() {
+		if !routed[path] {
+			t.Errorf("Для пути %s из описания нет обработчика", path)
+		}
+	}
+
+	// Подтест 20.2 каждый маршрут API сервера описан
+	source, err := os.ReadFile("main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range regexp.MustCompile(`Mux\.HandleFunc\("([^"]+)"`).FindAllStringSubmatch(string(source), -1) {
+		route := m[1]
+		if !strings.HasPrefix(route, "/api/") && !strings.HasPrefix(route, "/admin/") && route != "/graphql" && route != "/openapi.json" {
+			continue
+		}
+		described := false
+		for path := range spec.paths() {
+			if path == route || (strings.HasSuffix(route, "/") && strings.HasPrefix(path, route)) {
+				described = true
+			}
+		}
+		if !described {
+			t.Errorf("Маршрут %s не описан в /openapi.json", route)
+		}
+	}
+
+	// Подтест 20.3 ответы обработчиков соответствуют схемам
+	hook, err := pkg.RegisterWebhook("https://example.com/hook", []int{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, h := range pkg.ListWebhooks() {
+			pkg.DeleteWebhook(h.ID)
+		}
+	}()
+
+	cases := []struct {
+		method, specPath, target, body string
+		status                         int
+	}{
+		{"GET", "/api/bands", "/api/bands?sort=name&per_page=1", "", 200},
+		{"GET", "/api/bands", "/api/bands?sort=age", "", 400},
+		{"POST", "/api/bands", "/api/bands", "", 405},
+		{"GET", "/api/search", "/api/search?query=queen", "", 200},
+		{"GET", "/api/search", "/api/search?query=nothing", "", 200},
+		{"GET", "/api/search", "/api/search?query=", "", 200},
+		{"GET", "/api/search", "/api/search?query=formed+before+1x70", "", 400},
+		{"GET", "/api/search", "/api/search?query=q&order=up", "", 400},
+		{"GET", "/api/suggestions", "/api/suggestions", "", 200},
+		{"GET", "/api/band", "/api/band?id=1", "", 200},
+		{"GET", "/api/band", "/api/band?id=x", "", 400},
+		{"GET", "/api/band", "/api/band?id=99", "", 404},
+		{"GET", "/api/history", "/api/history", "", 200},
+		{"GET", "/api/history/diff", "/api/history/diff?from=none&to=none", "", 404},
+		{"GET", "/api/quality", "/api/quality", "", 200},
+		{"GET", "/api/webhooks", "/api/webhooks", "", 200},
+		{"POST", "/api/webhooks", "/api/webhooks", `{"url": "https://example.com/other", "locations": ["germany"]}`, 201},
+		{"POST", "/api/webhooks", "/api/webhooks", `{"url": "ftp://example.com"}`, 400},
+		{"GET", "/api/webhooks/deliveries", "/api/webhooks/deliveries", "", 200},
+		{"DELETE", "/api/webhooks/{id}", "/api/webhooks/unknown", "", 404},
+		{"DELETE", "/api/webhooks/{id}", "/api/webhooks/" + hook.ID, "", 204},
+		{"GET", "/graphql", "/graphql?query=" + strings.ReplaceAll("{ bands { name } }", " ", "%20"), "", 200},
+		{"POST", "/graphql", "/graphql", `{"query": "{ band(id: 1) { name concerts { date } } }"}`, 200},
+		{"POST", "/graphql", "/graphql", `{"query": "{ unknown }"}`, 400},
+		{"GET", "/admin/cache", "/admin/cache?format=json", "", 200},
+		{"POST", "/admin/rollback", "/admin/rollback?format=json", "", 409},
+		{"GET", "/openapi.json", "/openapi.json", "", 200},
+	}
+
+	covered := map[string]bool{}
+	for _, c := range cases {
+		covered[c.method+" "+c.specPath] = true
+
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		req.SetBasicAuth("admin", "secret")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		name := c.method + " " + c.target
+		if rr.Code != c.status {
+			t.Errorf("%s: ожидался статус %d, получен %d: %s", name, c.status, rr.Code, rr.Body.String())
+			continue
+		}
+
+		schema, ok := spec.responseSchema(c.specPath, c.method, rr.Code)
+		if !ok {
+			t.Errorf("%s: статус %d не описан", name, rr.Code)
+			continue
+		}
+		if schema == nil {
+			continue
+		}
+		if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("%s: ожидался ответ JSON, получен %q", name, ct)
+			continue
+		}
+
+		var got interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Errorf("%s: ответ не является JSON: %v", name, err)
+			continue
+		}
+		for _, problem := range spec.validate(schema, got, "$") {
+			t.Errorf("%s: %s", name, problem)
+		}
+	}
+
+	// Подтест 20.4 каждая операция с ответом JSON проверена
+	for path, item := range spec.paths() {
+		for method, op := range item.(map[string]interface{}) {
+			key := strings.ToUpper(method) + " " + path
+			if covered[key] || openAPISkipped[key] != "" {
+				continue
+			}
+			for _, resp := range op.(map[string]interface{})["responses"].(map[string]interface{}) {
+				content, _ := spec.resolve(resp.(map[string]interface{}))["content"].(map[string]interface{})
+				if _, ok := content["application/json"]; ok {
+					t.Errorf("Операция %s не проверяется тестом", key)
+					break
+				}
+			}
+		}
+	}
+}
+
+// Тест 21 для проверки клиента API
+func TestClient(t *testing.T) {
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	t.Setenv("WEBHOOKS_FILE", filepath.Join(t.TempDir(), "webhooks.json"))
+	t.Setenv("ADMIN_USER", "admin")
+	t.Setenv("ADMIN_PASSWORD", "secret")
+
+	mux := http.NewServeMux()
+	registered := map[string]bool{}
+	for _, route := range openAPIRoutes {
+		if !registered[route.pattern] {
+			mux.HandleFunc(route.pattern, route.handler)
+			registered[route.pattern] = true
+		}
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	c := client.New(server.URL+"/", server.Client())
+
+	// Подтест 21.1 список групп с курсором
+	page, err := c.Bands(ctx, client.ListOptions{Sort: "name", PerPage: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Items) != 1 || page.Items[0].Name != "Queen" || page.NextCursor == "" {
+		t.Errorf("Неожиданная первая страница: %+v", page)
+	}
+	page, err = c.Bands(ctx, client.ListOptions{Sort: "name", PerPage: 1, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "SOJA" {
+		t.Errorf("Неожиданная вторая страница: %+v", page)
+	}
+
+	// Подтест 21.2 группа, поиск и подсказки
+	band, err := c.Band(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if band.Name != "Queen" || band.FirstAlbumDate != "1973-12-14" || len(band.Concerts) != 2 || band.Tour == nil || band.Tour.Shows != 2 {
+		t.Errorf("Неожиданная группа: %+v", band)
+	}
+
+	found, err := c.Search(ctx, "jacob", client.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Total != 1 || found.Items[0].ID != 2 {
+		t.Errorf("Неожиданный результат поиска: %+v", found)
+	}
+
+	suggestions, err := c.Suggestions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions.Names) != 2 || len(suggestions.Members) != 3 {
+		t.Errorf("Неожиданные подсказки: %+v", suggestions)
+	}
+
+	// Подтест 21.3 ошибки сервера возвращаются как APIError
+	_, err = c.Band(ctx, 99)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Not Found" {
+		t.Errorf("Ожидалась ошибка 404, получено %v", err)
+	}
+	if _, err := c.Webhooks(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Ожидалась ошибка 401 без пароля, получено %v", err)
+	}
+
+	// Подтест 21.4 подписки на уведомления
+	c.SetBasicAuth("admin", "secret")
+	hook, err := c.CreateWebhook(ctx, client.WebhookRequest{URL: "https://example.com/hook", BandIDs: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.ID == "" || hook.Secret == "" {
+		t.Errorf("Неожиданная подписка: %+v", hook)
+	}
+	hooks, err := c.Webhooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != hook.ID {
+		t.Errorf("Ожидалась одна подписка, получено %+v", hooks)
+	}
+	if err := c.DeleteWebhook(ctx, hook.ID); err != nil {
+		t.Error(err)
+	}
+
+	// Подтест 21.5 запрос GraphQL
+	var data struct {
+		Band struct {
+			Name    string `json:"name"`
+			Members []struct {
+				Name string `json:"name"`
+			} `json:"members"`
+		} `json:"band"`
+	}
+	gqlErrs, err := c.GraphQL(ctx, `query($id: Int!) { band(id: $id) { name members { name } } }`, map[string]interface{}{"id": 2}, &data)
+	if err != nil || len(gqlErrs) != 0 {
+		t.Fatalf("Ошибка запроса GraphQL: %v %v", err, gqlErrs)
+	}
+	if data.Band.Name != "SOJA" || len(data.Band.Members) != 1 {
+		t.Errorf("Неожиданный ответ GraphQL: %+v", data)
+	}
+	gqlErrs, err = c.GraphQL(ctx, `{ unknown }`, nil, nil)
+	if err != nil || len(gqlErrs) == 0 {
+		t.Errorf("Ожидались ошибки GraphQL, получено %v %v", err, gqlErrs)
+	}
+}
@@ -0,0 +1,128 @@
+package pkg_test
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 9 для проверки сортировки и постраничного вывода
+func TestPaginateBands(t *testing.T) {
+	bands := []pkg.Band{
+		{ID: 1, Name: "Queen", CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "ABBA", CreationDate: 1972, FirstAlbum: "30-03-1973"},
+		{ID: 3, Name: "Pink Floyd", CreationDate: 1970, FirstAlbum: "05-08-1967"},
+	}
+	pkg.ParseFirstAlbums(bands)
+
+	opts, err := pkg.ParseListOptions(httptest.NewRequest("GET", "/?sort=creationDate&order=desc&per_page=2", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := pkg.PaginateBands(bands, opts)
+	if page.Total != 3 || page.TotalPages != 2 || len(page.Items) != 2 {
+		t.Fatalf("Неверная страница: %+v", page)
+	}
+	// При равной дате создания порядок определяется номером группы
+	if page.Items[0].ID != 2 || page.Items[1].ID != 1 {
+		t.Errorf("Неверный порядок групп: %v, %v", page.Items[0].ID, page.Items[1].ID)
+	}
+
+	// Следующая страница по курсору
+	cursor := page.NextCursor
+	if cursor == "" {
+		t.Fatal("Для первой страницы нет курсора следующей")
+	}
+	opts, err = pkg.ParseListOptions(httptest.NewRequest("GET", "/?sort=creationDate&order=desc&per_page=2&cursor="+cursor, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page = pkg.PaginateBands(bands, opts)
+	if len(page.Items) != 1 || page.Items[0].ID != 3 || page.NextCursor != "" {
+		t.Errorf("Неверная вторая страница: %+v", page)
+	}
+
+	// Курсор не подходит к другой сортировке или другому направлению
+	for _, query := range []string{"/?sort=name&order=desc&per_page=2", "/?sort=creationDate&per_page=2"} {
+		if _, err = pkg.ParseListOptions(httptest.NewRequest("GET", query+"&cursor="+cursor, nil)); !errors.Is(err, pkg.ErrInvalidListOptions) {
+			t.Errorf("%s: ожидалась ошибка для чужого курсора, получено %v", query, err)
+		}
+	}
+
+	sorted := pkg.SortBands(bands, "firstAlbum", false)
+	if sorted[0].ID != 3 || sorted[2].ID != 1 {
+		t.Errorf("Неверная сортировка по первому альбому: %v", sorted)
+	}
+
+	if _, err = pkg.ParseListOptions(httptest.NewRequest("GET", "/?per_page=1000", nil)); err == nil {
+		t.Errorf("Ожидалась ошибка для слишком большой страницы")
+	}
+
+	// Огромный номер страницы дает пустую страницу, а не переполнение смещения
+	huge := "page=" + strconv.Itoa(math.MaxInt) + "&per_page=2"
+	opts, err = pkg.ParseListOptions(httptest.NewRequest("GET", "/?"+huge, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page = pkg.PaginateBands(bands, opts); len(page.Items) != 0 || page.Page != math.MaxInt || page.TotalPages != 2 {
+		t.Errorf("Неверная страница за пределами списка: %+v", page)
+	}
+
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(bands)
+
+	handlers := []struct {
+		target  string
+		handler http.HandlerFunc
+	}{
+		{"/?" + huge, pkg.HomeHandler},
+		{"/search?query=a&" + huge, pkg.SearchHandler},
+		{"/api/bands?" + huge, pkg.APIBandsHandler},
+		{"/api/search?query=a&" + huge, pkg.APISearchHandler},
+	}
+	for _, h := range handlers {
+		rr := httptest.NewRecorder()
+		h.handler(rr, httptest.NewRequest("GET", h.target, nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: ожидался статус 200, получен %d", h.target, rr.Code)
+		}
+	}
+	// Форма сортировки на страницах отправляет только значения, которые принимает сервер
+	rr := httptest.NewRecorder()
+	pkg.HomeHandler(rr, httptest.NewRequest("GET", "/", nil))
+	form := regexp.MustCompile(`(?s)<select name="sort">(.*?)</select>`).FindStringSubmatch(rr.Body.String())
+	if form == nil {
+		t.Fatal("На главной странице нет формы сортировки")
+	}
+	values := regexp.MustCompile(`<option value="([^"]*)"`).FindAllStringSubmatch(form[1], -1)
+	if len(values) == 0 || values[0][1] != "id" {
+		t.Fatalf("Первым в форме должен быть порядок по умолчанию: %v", values)
+	}
+	for _, v := range values {
+		for _, h := range handlers {
+			target := strings.Replace(h.target, huge, "sort="+v[1]+"&order=desc", 1)
+			rr := httptest.NewRecorder()
+			h.handler(rr, httptest.NewRequest("GET", target, nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("%s: ожидался статус 200, получен %d", target, rr.Code)
+			}
+		}
+	}
+
+	opts, err = pkg.ParseListOptions(httptest.NewRequest("GET", "/?sort=id&order=desc", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page = pkg.PaginateBands(bands, opts); page.Items[0].ID != 3 || page.Items[2].ID != 1 {
+		t.Errorf("Неверный порядок по убыванию номера: %v", page.Items)
+	}
+}
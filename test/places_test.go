@@ -0,0 +1,120 @@
+package pkg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 30 для проверки справочника стран и городов
+func TestPlaces(t *testing.T) {
+	bands := []pkg.Band{
+		{ID: 1, Name: "Queen", Relations: map[string][]string{
+			"london-uk":      {"03-01-2020", "01-01-2020"},
+			"berlin-germany": {"05-01-2020"},
+		}},
+		{ID: 2, Name: "SOJA", Relations: map[string][]string{
+			"london-uk":       {"04-04-2021"},
+			"berlin-germany":  {"06-06-2021"},
+			"los_angeles-usa": {"01-01-2019", "02-01-2019", "03-01-2019", "04-01-2019", "05-01-2019"},
+		}},
+		{ID: 3, Name: "Pink Floyd", Relations: map[string][]string{
+			"manchester-uk": {"02-02-2020", "03-02-2020", "04-02-2020", "05-02-2020"},
+		}},
+	}
+
+	// Подтест 30.1 группы и концерты складываются по странам и городам
+	countries := map[string]pkg.CountrySummary{}
+	for _, c := range pkg.BuildPlaces(bands) {
+		countries[c.Key] = c
+	}
+	want := map[string][3]int{"uk": {2, 3, 7}, "germany": {1, 2, 2}, "usa": {1, 1, 5}}
+	if len(countries) != len(want) {
+		t.Errorf("Ожидалось %d страны, получено %v", len(want), countries)
+	}
+	for key, w := range want {
+		c := countries[key]
+		if got := [3]int{len(c.Cities), c.Bands, c.Concerts}; got != w {
+			t.Errorf("%s: ожидалось городов, групп и концертов %v, получено %v", key, w, got)
+		}
+	}
+	if name := countries["usa"].Cities[0].Name; name != "Los Angeles" {
+		t.Errorf("Неверное название города: %q", name)
+	}
+
+	var london pkg.CitySummary
+	for _, city := range countries["uk"].Cities {
+		if city.Key == "london" {
+			london = city
+		}
+	}
+	if london.Concerts != 3 || len(london.Bands) != 2 || london.Bands[0].Name != "Queen" || london.Bands[1].Name != "SOJA" {
+		t.Fatalf("Неверная сводка по Лондону: %+v", london)
+	}
+	if dates := strings.Join(london.Bands[0].Dates, " "); dates != "01-01-2020 03-01-2020" {
+		t.Errorf("Даты концертов должны идти по порядку, получено %s", dates)
+	}
+
+	// Подтест 30.2 сортировка стран и городов на страницах локаций
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(bands)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		pkg.LocationsHandler(rr, httptest.NewRequest("GET", target, nil))
+		return rr
+	}
+	order := func(target, prefix string) string {
+		t.Helper()
+		rr := serve(target)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: ожидался статус 200, получен %d", target, rr.Code)
+		}
+		var keys []string
+		for _, m := range regexp.MustCompile(`href="`+prefix+`([a-z_]+)"`).FindAllStringSubmatch(rr.Body.String(), -1) {
+			keys = append(keys, m[1])
+		}
+		return strings.Join(keys, " ")
+	}
+
+	sorts := []struct {
+		target, prefix, want string
+	}{
+		{"/locations", "/locations/", "germany uk usa"},
+		{"/locations?sort=name", "/locations/", "germany uk usa"},
+		{"/locations?sort=bands", "/locations/", "uk germany usa"},
+		{"/locations?sort=concerts", "/locations/", "uk usa germany"},
+		{"/locations?sort=unknown", "/locations/", "germany uk usa"},
+		{"/locations/uk", "/locations/uk/", "london manchester"},
+		{"/locations/uk?sort=bands", "/locations/uk/", "london manchester"},
+		{"/locations/uk?sort=concerts", "/locations/uk/", "manchester london"},
+		{"/locations/UK?sort=concerts", "/locations/uk/", "manchester london"},
+	}
+	for _, s := range sorts {
+		if got := order(s.target, s.prefix); got != s.want {
+			t.Errorf("%s: ожидался порядок %q, получено %q", s.target, s.want, got)
+		}
+	}
+
+	body := serve("/locations/uk/london").Body.String()
+	if queen, soja := strings.Index(body, ">Queen<"), strings.Index(body, ">SOJA<"); queen < 0 || soja < queen {
+		t.Errorf("Группы в городе должны идти по названию:\n%s", body)
+	}
+
+	// Подтест 30.3 неизвестные страны, города и лишние части пути
+	for _, target := range []string{"/locations/france", "/locations/uk/paris", "/locations/uk/london/extra", "/locationsx"} {
+		if rr := serve(target); rr.Code != http.StatusNotFound {
+			t.Errorf("%s: ожидался статус 404, получен %d", target, rr.Code)
+		}
+	}
+	rr := httptest.NewRecorder()
+	pkg.LocationsHandler(rr, httptest.NewRequest("POST", "/locations", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Ожидался статус 405, получен %d", rr.Code)
+	}
+}
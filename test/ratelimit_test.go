@@ -0,0 +1,171 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 26 для проверки ограничения частоты запросов
+func TestRateLimit(t *testing.T) {
+	// Подтест 26.1 разбор настройки лимитов
+	limits, err := pkg.ParseRateLimits("/search=2/s:5, /api/=120/m, /web/static/=off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pkg.RouteLimit{
+		{Prefix: "/search", Rate: 2, Burst: 5},
+		{Prefix: "/api/", Rate: 2, Burst: 120},
+		{Prefix: "/web/static/"},
+	}
+	if len(limits) != len(want) {
+		t.Fatalf("Ожидалось %d лимита, получено %+v", len(want), limits)
+	}
+	for i := range want {
+		if limits[i] != want[i] {
+			t.Errorf("Лимит %d: ожидалось %+v, получено %+v", i, want[i], limits[i])
+		}
+	}
+	for _, spec := range []string{"search=1/s", "/search=1", "/search=0/s", "/search=1/d", "/search=1/s:0"} {
+		if _, err := pkg.ParseRateLimits(spec); !errors.Is(err, pkg.ErrInvalidRateLimit) {
+			t.Errorf("Ожидалась ошибка для %q, получено %v", spec, err)
+		}
+	}
+
+	// Подтест 26.2 корзина токенов: запросы подряд, ожидание и независимые адреса
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := pkg.NewRateLimiter([]pkg.RouteLimit{
+		{Prefix: "/", Rate: 100, Burst: 100},
+		{Prefix: "/search", Rate: 1, Burst: 2},
+		{Prefix: "/web/static/"},
+	})
+	limiter.Now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("/search", "10.0.0.1"); !ok {
+			t.Fatalf("Запрос %d должен пройти", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("/search", "10.0.0.1")
+	if ok || wait != time.Second {
+		t.Errorf("Третий запрос подряд должен ждать 1с, получено %v %v", ok, wait)
+	}
+	if ok, _ := limiter.Allow("/search", "10.0.0.2"); !ok {
+		t.Errorf("Лимит другого адреса не должен расходоваться")
+	}
+	if ok, _ := limiter.Allow("/band", "10.0.0.1"); !ok {
+		t.Errorf("Лимит другого маршрута не должен расходоваться")
+	}
+	for i := 0; i < 1000; i++ {
+		if ok, _ := limiter.Allow("/web/static/styles.css", "10.0.0.1"); !ok {
+			t.Fatal("Маршрут без лимита не должен ограничиваться")
+		}
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if ok, _ := limiter.Allow("/search", "10.0.0.1"); !ok {
+		t.Errorf("Через секунду должен появиться токен")
+	}
+	if ok, wait := limiter.Allow("/search", "10.0.0.1"); ok || wait != 500*time.Millisecond {
+		t.Errorf("Ожидалось ожидание 0.5с, получено %v %v", ok, wait)
+	}
+
+	// Подтест 26.3 ответ 429 с Retry-After: страница для сайта и JSON для API
+	t.Setenv("RATE_LIMITS", "/search=1/m:1,/api/search=1/m:1")
+
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", pkg.SearchHandler)
+	mux.HandleFunc("/api/search", pkg.APISearchHandler)
+	handler := pkg.RateLimit(mux)
+
+	serve := func(target, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = remote
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve("/search?query=queen", "192.0.2.1:1234"); rr.Code != http.StatusOK {
+		t.Fatalf("Первый запрос должен пройти, получен статус %d", rr.Code)
+	}
+	rr := serve("/search?query=queen&lang=ru", "192.0.2.1:4321")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Ожидался статус 429 с Retry-After 60, получено %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(rr.Body.String(), "Повторите через 60 с.") {
+		t.Errorf("Ожидалась страница ошибки с временем ожидания")
+	}
+
+	serve("/api/search?query=queen", "192.0.2.1:1234")
+	rr = serve("/api/search?query=queen", "192.0.2.1:1234")
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusTooManyRequests || resp.Error != "Too Many Requests" {
+		t.Errorf("Ожидалась ошибка JSON 429, получено %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Подтест 26.4 адрес из X-Forwarded-For учитывается только за доверенным прокси
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:80"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+	if ip := pkg.ClientIP(req); ip != "10.0.0.1" {
+		t.Errorf("Без TRUST_PROXY ожидался адрес соединения, получено %s", ip)
+	}
+	t.Setenv("TRUST_PROXY", "1")
+	if ip := pkg.ClientIP(req); ip != "203.0.113.9" {
+		t.Errorf("С TRUST_PROXY ожидался адрес, добавленный прокси, получено %s", ip)
+	}
+
+	// Подтест 26.5 слишком длинный поисковый запрос
+	long := strings.Repeat("я", pkg.MaxQueryLength+1)
+	rr = httptest.NewRecorder()
+	pkg.SearchHandler(rr, httptest.NewRequest("GET", "/search?query="+long, nil))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "longer than 200 characters") {
+		t.Errorf("Ожидался статус 400 для длинного запроса, получен %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	pkg.APISearchHandler(rr, httptest.NewRequest("GET", "/api/search?query="+long, nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Ожидался статус 400 для длинного запроса к API, получен %d", rr.Code)
+	}
+	if _, err := pkg.SearchRecords(pkg.SnapshotBands(), long); !errors.Is(err, pkg.ErrQueryTooLong) {
+		t.Errorf("Ожидалась ошибка длинного запроса, получено %v", err)
+	}
+	rr = httptest.NewRecorder()
+	pkg.SearchHandler(rr, httptest.NewRequest("GET", "/search?query="+strings.Repeat("я", pkg.MaxQueryLength), nil))
+	if rr.Code == http.StatusBadRequest {
+		t.Errorf("Запрос максимальной длины должен приниматься")
+	}
+
+	// Подтест 26.6 одновременные запросы не видят запросы друг друга
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pkg.SearchHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/search?query="+long, nil))
+		}()
+		go func() {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			pkg.SearchHandler(rr, httptest.NewRequest("GET", "/search?query=queen", nil))
+			if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `name="query" value="queen"`) {
+				t.Errorf("Ожидалась страница поиска queen, получен статус %d", rr.Code)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,216 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Маршруты сервера из main.go и запрос, который проверяется для каждого из них
+var securityRoutes = []struct {
+	pattern string
+	target  string
+	handler http.Handler
+}{
+	{"/", "/", http.HandlerFunc(pkg.HomeHandler)},
+	{"/band", "/band?id=1", http.HandlerFunc(pkg.BandHandler)},
+	{"/band/", "/band/1/calendar.ics", http.HandlerFunc(pkg.BandCalendarHandler)},
+	{"/calendar.ics", "/calendar.ics?bands=1", http.HandlerFunc(pkg.CombinedCalendarHandler)},
+	{"/search", "/search?query=queen", http.HandlerFunc(pkg.SearchHandler)},
+	{"/img/", "/img/999", http.HandlerFunc(pkg.ImageHandler)},
+	{"/export/", "/export/bands.csv", http.HandlerFunc(pkg.ExportHandler)},
+	{"/locations", "/locations", http.HandlerFunc(pkg.LocationsHandler)},
+	{"/locations/", "/locations/germany", http.HandlerFunc(pkg.LocationsHandler)},
+	{"/members", "/members", http.HandlerFunc(pkg.MembersHandler)},
+	{"/members/", "/members/freddie-mercury", http.HandlerFunc(pkg.MembersHandler)},
+	{"/quality", "/quality", http.HandlerFunc(pkg.QualityHandler)},
+	{"/api/quality", "/api/quality", http.HandlerFunc(pkg.APIQualityHandler)},
+	{"/api/bands", "/api/bands", http.HandlerFunc(pkg.APIBandsHandler)},
+	{"/api/band", "/api/band?id=1", http.HandlerFunc(pkg.APIBandHandler)},
+	{"/api/search", "/api/search?query=queen", http.HandlerFunc(pkg.APISearchHandler)},
+	{"/api/suggestions", "/api/suggestions?query=qu", http.HandlerFunc(pkg.APISuggestionsHandler)},
+	{"/openapi.json", "/openapi.json", http.HandlerFunc(pkg.OpenAPIHandler)},
+	{"/graphql", "/graphql", http.HandlerFunc(pkg.GraphQLHandler)},
+	{"/events", "/events", http.HandlerFunc(pkg.EventsHandler)},
+	{"/register", "/register", http.HandlerFunc(pkg.RegisterHandler)},
+	{"/login", "/login", http.HandlerFunc(pkg.LoginHandler)},
+	{"/logout", "/logout", http.HandlerFunc(pkg.LogoutHandler)},
+	{"/follow", "/follow", http.HandlerFunc(pkg.FollowHandler)},
+	{"/my", "/my", http.HandlerFunc(pkg.MyHandler)},
+	{"/feed.atom", "/feed.atom", http.HandlerFunc(pkg.AtomFeedHandler)},
+	{"/feed.rss", "/feed.rss", http.HandlerFunc(pkg.RSSFeedHandler)},
+	{"/history", "/history", http.HandlerFunc(pkg.HistoryHandler)},
+	{"/api/history", "/api/history", http.HandlerFunc(pkg.APIHistoryHandler)},
+	{"/api/history/diff", "/api/history/diff", http.HandlerFunc(pkg.APIHistoryDiffHandler)},
+	{"/api/webhooks", "/api/webhooks", pkg.AdminAuth(pkg.WebhooksHandler)},
+	{"/api/webhooks/", "/api/webhooks/deliveries", pkg.AdminAuth(pkg.WebhookHandler)},
+	{"/admin/cache", "/admin/cache", pkg.AdminAuth(pkg.AdminCacheHandler)},
+	{"/admin/refresh", "/admin/refresh", pkg.AdminAuth(pkg.AdminRefreshHandler)},
+	{"/admin/rollback", "/admin/rollback", pkg.AdminAuth(pkg.AdminRollbackHandler)},
+	{"/web/static/", "/web/static/styles.css", http.StripPrefix("/web/static/", http.FileServer(http.Dir("web/static")))},
+}
+
+// Функция проверки заголовков безопасности ответа
+func checkSecurityHeaders(t *testing.T, name string, rr *httptest.ResponseRecorder) {
+	t.Helper()
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	for _, directive := range []string{"default-src 'self'", "script-src 'self' 'nonce-", "object-src 'none'", "frame-ancestors 'none'"} {
+		if !strings.Contains(csp, directive) {
+			t.Errorf("%s: в Content-Security-Policy нет %q: %q", name, directive, csp)
+		}
+	}
+	want := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+	}
+	for header, value := range want {
+		if got := rr.Header().Get(header); got != value {
+			t.Errorf("%s: ожидался %s %q, получено %q", name, header, value, got)
+		}
+	}
+}
+
+// Тест 27 для проверки заголовков безопасности
+func TestSecurityHeaders(t *testing.T) {
+	// Подтест 27.1 политика по умолчанию и настройка через переменные окружения
+	policy, err := pkg.SecurityPolicyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "default-src 'self'; script-src 'self' 'nonce-abc'; style-src 'self'; img-src 'self'; connect-src 'self'; " +
+		"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+	if got := policy.ContentSecurityPolicy("abc"); got != want {
+		t.Errorf("Неожиданная политика по умолчанию: %q", got)
+	}
+
+	t.Setenv("CSP_IMG_SRC", "https://groupietrackers.herokuapp.com, data:")
+	t.Setenv("HSTS_MAX_AGE", "3600")
+	if policy, err = pkg.SecurityPolicyFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if got := policy.ContentSecurityPolicy("abc"); !strings.Contains(got, "img-src 'self' https://groupietrackers.herokuapp.com data:;") {
+		t.Errorf("Источники изображений не добавлены в политику: %q", got)
+	}
+
+	t.Setenv("CSP_IMG_SRC", "https://example.com; script-src *")
+	if _, err := pkg.SecurityPolicyFromEnv(); !errors.Is(err, pkg.ErrInvalidCSPSource) {
+		t.Errorf("Ожидалась ошибка для источника с точкой с запятой, получено %v", err)
+	}
+	t.Setenv("CSP_IMG_SRC", "")
+
+	t.Setenv("CONTENT_SECURITY_POLICY", "default-src 'none'; script-src 'nonce-{nonce}'")
+	if policy, err = pkg.SecurityPolicyFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if got := policy.ContentSecurityPolicy("abc"); got != "default-src 'none'; script-src 'nonce-abc'" {
+		t.Errorf("Политика из CONTENT_SECURITY_POLICY не применена: %q", got)
+	}
+	t.Setenv("CONTENT_SECURITY_POLICY", "")
+
+	// Подтест 27.2 заголовки на каждом маршруте сервера
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData(openAPIFixture())
+
+	t.Setenv("USERS_FILE", filepath.Join(t.TempDir(), "users.json"))
+	t.Setenv("WEBHOOKS_FILE", filepath.Join(t.TempDir(), "webhooks.json"))
+	t.Setenv("IMAGE_CACHE_DIR", t.TempDir())
+	t.Setenv("ADMIN_PASSWORD", "secret")
+	t.Setenv("RATE_LIMITS", "/search=1/m:1")
+
+	mux := http.NewServeMux()
+	for _, route := range securityRoutes {
+		mux.Handle(route.pattern, route.handler)
+	}
+	handler := pkg.SecurityHeaders(pkg.WithLocale(pkg.RateLimit(mux)))
+
+	source, err := os.ReadFile("main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range regexp.MustCompile(`Mux\.Handle(?:Func)?\("([^"]+)"`).FindAllStringSubmatch(string(source), -1) {
+		covered := false
+		for _, route := range securityRoutes {
+			covered = covered || route.pattern == m[1]
+		}
+		if !covered {
+			t.Errorf("Маршрут %s не проверяется тестом заголовков безопасности", m[1])
+		}
+	}
+
+	serve := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithCancel(context.Background())
+		// Поток событий /events завершается сразу, как только закрыт запрос
+		cancel()
+		req := httptest.NewRequest(method, target, nil).WithContext(ctx)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, route := range securityRoutes {
+		checkSecurityHeaders(t, route.target, serve("GET", route.target, nil))
+	}
+
+	// Подтест 27.3 заголовки на страницах ошибок: 404, 405, 401 и 429
+	errorPages := []struct {
+		method, target string
+		status         int
+	}{
+		{"GET", "/no-such-page", http.StatusNotFound},
+		{"GET", "/band?id=999", http.StatusNotFound},
+		{"DELETE", "/band?id=1", http.StatusMethodNotAllowed},
+		{"GET", "/api/band?id=999", http.StatusNotFound},
+		{"GET", "/admin/cache", http.StatusUnauthorized},
+		{"GET", "/search?query=queen", http.StatusTooManyRequests},
+	}
+	for _, page := range errorPages {
+		rr := serve(page.method, page.target, nil)
+		if rr.Code != page.status {
+			t.Errorf("%s %s: ожидался статус %d, получен %d", page.method, page.target, page.status, rr.Code)
+		}
+		checkSecurityHeaders(t, page.target, rr)
+	}
+
+	// Подтест 27.4 nonce скрипта совпадает с политикой и меняется от запроса к запросу
+	nonces := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		rr := serve("GET", "/band?id=1", nil)
+		m := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(rr.Header().Get("Content-Security-Policy"))
+		if m == nil {
+			t.Fatalf("В политике нет nonce: %q", rr.Header().Get("Content-Security-Policy"))
+		}
+		if !strings.Contains(rr.Body.String(), `<script src="/web/static/live.js" nonce="`+m[1]+`">`) {
+			t.Errorf("Скрипт на странице группы без nonce из политики")
+		}
+		if strings.Contains(rr.Body.String(), "style=") {
+			t.Errorf("На странице группы остались встроенные стили, которые запрещает style-src 'self'")
+		}
+		nonces[m[1]] = true
+	}
+	if len(nonces) != 2 {
+		t.Errorf("Nonce должен быть новым для каждого запроса")
+	}
+
+	// Подтест 27.5 Strict-Transport-Security только для HTTPS
+	if hsts := serve("GET", "/", nil).Header().Get("Strict-Transport-Security"); hsts != "" {
+		t.Errorf("Strict-Transport-Security не должен отправляться по HTTP, получено %q", hsts)
+	}
+	https := http.Header{"X-Forwarded-Proto": {"https"}}
+	if hsts := serve("GET", "/", https).Header().Get("Strict-Transport-Security"); hsts != "max-age=3600" {
+		t.Errorf("Ожидался Strict-Transport-Security max-age=3600, получено %q", hsts)
+	}
+}
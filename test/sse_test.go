@@ -0,0 +1,201 @@
+package pkg_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Ответ, запись в который блокируется после первого события: так ведет себя клиент,
+// который перестал читать поток
+type stalledWriter struct {
+	header  http.Header
+	mu      sync.Mutex
+	writes  int
+	release chan struct{}
+}
+
+func (w *stalledWriter) Header() http.Header { return w.header }
+
+func (w *stalledWriter) WriteHeader(int) {}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.writes++
+	stalled := w.writes > 1
+	w.mu.Unlock()
+
+	if stalled {
+		<-w.release
+	}
+	return len(p), nil
+}
+
+func (w *stalledWriter) Flush() {}
+
+// Функция ожидания условия с ограничением по времени
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Не дождались: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Функция чтения одного события из потока: строки до пустой строки
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Поток событий оборвался: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// Тест 28 для проверки потока событий об обновлении данных
+func TestEventStream(t *testing.T) {
+	savedBands, savedRelations, savedLocations := pkg.BandInfo, pkg.RelationInfo, pkg.LocationInfo
+	savedData := pkg.ResponseData
+	defer func() {
+		pkg.BandInfo, pkg.RelationInfo, pkg.LocationInfo = savedBands, savedRelations, savedLocations
+		pkg.ResponseData = savedData
+	}()
+
+	dir := t.TempDir()
+	t.Setenv("DB_FILE", filepath.Join(dir, "groupie.db"))
+	t.Setenv("USERS_FILE", filepath.Join(dir, "users.json"))
+	t.Setenv("WEBHOOKS_FILE", filepath.Join(dir, "webhooks.json"))
+	if err := pkg.OpenStorage(); err != nil {
+		t.Fatal(err)
+	}
+	defer pkg.CloseStorage()
+
+	// Данные меняются так же, как при обновлении: новый снимок загружается в кэш.
+	// Группы без года основания проверка данных отбрасывает, а без концертов - предупреждает.
+	snapshot := func(bands ...pkg.Band) pkg.Snapshot {
+		dates := map[int]map[string][]string{}
+		for _, b := range bands {
+			dates[b.ID] = map[string][]string{"london-uk": {"01-01-2020"}}
+		}
+		return pkg.Snapshot{Bands: bands, Relations: relations(dates)}
+	}
+	queen := pkg.Band{ID: 1, Name: "Queen", CreationDate: 1970}
+	soja := pkg.Band{ID: 2, Name: "SOJA", CreationDate: 1997}
+	snapshots := []pkg.Snapshot{
+		snapshot(queen, soja),
+		snapshot(queen, soja, pkg.Band{ID: 3, Name: "Pink Floyd", CreationDate: 1965}),
+	}
+	refresh := func(i int) {
+		t.Helper()
+		if err := pkg.Storage().SaveSnapshot(snapshots[i%2]); err != nil {
+			t.Fatal(err)
+		}
+		if err := pkg.LoadFromStorage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refresh(0)
+
+	// Подтест 28.1 формат потока: приветствие с версией данных и событие об изменениях
+	server := httptest.NewServer(http.HandlerFunc(pkg.EventsHandler))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Неверный ответ потока событий: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	version := pkg.SnapshotVersion()
+	hello := readEvent(t, reader)
+	want := []string{"event: hello", "id: " + strconv.FormatInt(version, 10), `data: {"version":` + strconv.FormatInt(version, 10) + `}`}
+	if strings.Join(hello, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Неверное приветствие: %q", hello)
+	}
+	if pkg.EventClientCount() != 1 {
+		t.Errorf("Ожидался один клиент, получено %d", pkg.EventClientCount())
+	}
+
+	refresh(1)
+	update := readEvent(t, reader)
+	want = []string{"event: update", "id: " + strconv.FormatInt(version+1, 10),
+		`data: {"version":` + strconv.FormatInt(version+1, 10) + `,"changedBands":[],"addedBands":[3],"removedBands":[]}`}
+	if strings.Join(update, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Неверное событие обновления: %q", update)
+	}
+
+	// Подтест 28.2 клиент удаляется после отключения
+	cancel()
+	waitFor(t, "удаление отключившегося клиента", func() bool { return pkg.EventClientCount() == 0 })
+
+	// Подтест 28.3 клиент, который не читает поток, отключается при переполнении очереди
+	stalled := &stalledWriter{header: http.Header{}, release: make(chan struct{})}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		pkg.EventsHandler(stalled, httptest.NewRequest("GET", "/events", nil))
+	}()
+	waitFor(t, "подключение клиента", func() bool { return pkg.EventClientCount() == 1 })
+
+	for i := 0; i < 12 && pkg.EventClientCount() > 0; i++ {
+		refresh(i)
+	}
+	waitFor(t, "отключение медленного клиента", func() bool { return pkg.EventClientCount() == 0 })
+	close(stalled.release)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Обработчик медленного клиента не завершился")
+	}
+
+	// Подтест 28.4 сверх предела клиентов сервер отвечает 503
+	savedMax := pkg.MaxEventClients
+	pkg.MaxEventClients = 1
+	defer func() { pkg.MaxEventClients = savedMax }()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, "GET", server.URL+"/events", nil)
+	first, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Body.Close()
+	readEvent(t, bufio.NewReader(first.Body))
+
+	rr := httptest.NewRecorder()
+	pkg.EventsHandler(rr, httptest.NewRequest("GET", "/events", nil))
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("Ожидался статус 503 с Retry-After, получено %d", rr.Code)
+	}
+	cancel()
+	waitFor(t, "отключение клиента", func() bool { return pkg.EventClientCount() == 0 })
+}
@@ -0,0 +1,164 @@
+package pkg_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 17 для проверки встроенного хранилища ключ-значение
+func TestKVStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := pkg.OpenKVStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *pkg.KVTx) error {
+		tx.Put("bands", "1", "Queen")
+		tx.Put("bands", "2", "SOJA")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Update(func(tx *pkg.KVTx) error {
+		tx.Delete("bands", "2")
+		return nil
+	})
+	db.Close()
+
+	// Оборванная транзакция в конце файла отбрасывается при открытии
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"op":"put","b":"bands","k":"3","v":"\"Pink Floyd\""}` + "\n" + `{"op":"put","b":"ba`)
+	f.Close()
+
+	db, err = pkg.OpenKVStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var name string
+	if ok, _ := db.Get("bands", "1", &name); !ok || name != "Queen" {
+		t.Errorf("Ожидалась группа Queen, получено %q", name)
+	}
+	if db.Len("bands") != 1 {
+		t.Errorf("Ожидалась одна группа, получено %d", db.Len("bands"))
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	db.Update(func(tx *pkg.KVTx) error { return tx.Put("bands", "4", "Gorillaz") })
+	if ok, _ := db.Get("bands", "4", &name); !ok || name != "Gorillaz" {
+		t.Errorf("Запись после сжатия не сохранилась")
+	}
+
+	ro, err := pkg.OpenKVStore(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ro.Len("bands") != 2 {
+		t.Errorf("Ожидалось две группы после сжатия, получено %d", ro.Len("bands"))
+	}
+	if err := ro.Update(func(tx *pkg.KVTx) error { return nil }); err != pkg.ErrStoreReadOnly {
+		t.Errorf("Ожидалась ошибка записи в хранилище только для чтения, получено %v", err)
+	}
+	// Поврежденная строка в середине журнала - ошибка, а не потеря следующих транзакций
+	broken := filepath.Join(t.TempDir(), "broken.db")
+	journal := `{"op":"put","b":"bands","k":"1","v":"\"Queen\""}` + "\n" + `{"op":"commit"}` + "\n" +
+		`{"op":"put","b":"ba` + "\n" +
+		`{"op":"put","b":"bands","k":"2","v":"\"SOJA\""}` + "\n" + `{"op":"commit"}` + "\n"
+	os.WriteFile(broken, []byte(journal), 0o644)
+	if _, err := pkg.OpenKVStore(broken, false); !errors.Is(err, pkg.ErrStoreCorrupt) {
+		t.Errorf("Ожидалась ошибка поврежденного журнала, получено %v", err)
+	}
+	if data, _ := os.ReadFile(broken); string(data) != journal {
+		t.Errorf("Поврежденный журнал не должен обрезаться при открытии")
+	}
+	// Второй процесс не может открыть хранилище для записи, в том числе после сжатия журнала
+	locked := filepath.Join(t.TempDir(), "locked.db")
+	owner, err := pkg.OpenKVStore(locked, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := owner.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pkg.OpenKVStore(locked, false); !errors.Is(err, pkg.ErrStoreLocked) {
+		t.Errorf("Ожидалась ошибка блокировки хранилища, получено %v", err)
+	}
+	if reader, err := pkg.OpenKVStore(locked, true); err != nil {
+		t.Errorf("Чтение не должно требовать блокировки: %v", err)
+	} else {
+		reader.Close()
+	}
+	owner.Close()
+	if second, err := pkg.OpenKVStore(locked, false); err != nil {
+		t.Errorf("После закрытия хранилище должно открываться: %v", err)
+	} else {
+		second.Close()
+	}
+}
+
+// Тест 18 для проверки хранилища групп, концертов и миграций
+func TestRepository(t *testing.T) {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	t.Setenv("USERS_FILE", usersPath)
+	os.WriteFile(usersPath, []byte(`{"users":[{"id":"u1","username":"alice","follows":[1]}]}`), 0o644)
+
+	repo, err := pkg.OpenRepository(filepath.Join(dir, "groupie.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := repo.Users()
+	if err != nil || len(users) != 1 || users[0].Username != "alice" {
+		t.Fatalf("Пользователи не перенесены миграцией: %v %v", users, err)
+	}
+
+	snapshot := pkg.Snapshot{
+		Bands: []pkg.Band{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, FirstAlbum: "14-12-1973"},
+			{ID: 2, Name: "SOJA", Members: []string{"Brian May"}},
+		},
+		Relations: relations(map[int]map[string][]string{
+			1: {"london-uk": {"01-01-2020"}, "paris-france": {"02-01-2020"}},
+		}),
+		Time:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Source: pkg.SourceAPI,
+	}
+	if err := repo.SaveSnapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	repo, err = pkg.OpenRepository(filepath.Join(dir, "groupie.db"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	band, ok, err := repo.Band(1)
+	if err != nil || !ok || band.Name != "Queen" || len(band.Relations) != 2 || band.FirstAlbumDate.Year() != 1973 {
+		t.Errorf("Неверные данные группы: %+v %v", band, err)
+	}
+	concerts, _ := repo.Concerts(1)
+	if len(concerts) != 2 {
+		t.Errorf("Ожидалось 2 концерта, получено %v", concerts)
+	}
+	members, _ := repo.Members()
+	if len(members) != 2 {
+		t.Errorf("Ожидалось 2 участника, получено %v", members)
+	}
+	s, ok, err := repo.LoadSnapshot()
+	if err != nil || !ok || len(s.Bands) != 2 || !s.Time.Equal(snapshot.Time) {
+		t.Errorf("Неверный снимок из хранилища: %+v %v", s, err)
+	}
+}
@@ -0,0 +1,40 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 7 для проверки расчета статистики гастролей
+func TestComputeTour(t *testing.T) {
+	stats := pkg.ComputeTour(map[string][]string{
+		"london-uk":      {"01-01-2020", "*10-01-2019"},
+		"paris-france":   {"05-01-2019"},
+		"berlin-germany": {"bad-date"},
+	})
+
+	if stats.Shows != 3 {
+		t.Fatalf("Ожидалось 3 концерта, получено %v", stats.Shows)
+	}
+	if stats.Route[0].Location != "paris-france" || stats.Route[2].Location != "london-uk" {
+		t.Errorf("Неверный порядок маршрута: %+v", stats.Route)
+	}
+	// Париж - Лондон около 344 км, затем концерт в том же городе
+	if stats.TotalDistanceKm < 330 || stats.TotalDistanceKm > 360 {
+		t.Errorf("Неверное общее расстояние: %v", stats.TotalDistanceKm)
+	}
+	if len(stats.Countries) != 2 {
+		t.Errorf("Ожидалось 2 страны, получено %v", stats.Countries)
+	}
+	if stats.BusiestYear != 2019 || stats.BusiestYearShows != 2 {
+		t.Errorf("Неверный самый активный год: %v (%v)", stats.BusiestYear, stats.BusiestYearShows)
+	}
+	// 05-01-2019 .. 01-01-2020 - 361 день на 2 промежутка
+	if stats.AverageGapDays != 180.5 {
+		t.Errorf("Неверный средний промежуток: %v", stats.AverageGapDays)
+	}
+	if len(stats.InvalidDates) != 1 {
+		t.Errorf("Ожидалась одна некорректная дата, получено %v", stats.InvalidDates)
+	}
+}
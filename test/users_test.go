@@ -0,0 +1,159 @@
+package pkg_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 15 для проверки регистрации, входа и подписки на группы
+func TestUsers(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("USERS_FILE", filepath.Join(dir, "users.json"))
+	t.Setenv("DB_FILE", filepath.Join(dir, "groupie.db"))
+	pkg.PasswordIterations = 1000
+	if err := pkg.OpenStorage(); err != nil {
+		t.Fatal(err)
+	}
+	defer pkg.CloseStorage()
+	if err := pkg.LoadUsers(); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := pkg.HashPassword("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(hash, "secret-password") || !pkg.CheckPassword(hash, "secret-password") || pkg.CheckPassword(hash, "other-password") {
+		t.Errorf("Неверная проверка хеша пароля %v", hash)
+	}
+
+	if _, err := pkg.RegisterUser("ab", "secret-password"); err != pkg.ErrInvalidUsername {
+		t.Errorf("Ожидалась ошибка имени пользователя, получено %v", err)
+	}
+	if _, err := pkg.RegisterUser("alice", "short"); err != pkg.ErrWeakPassword {
+		t.Errorf("Ожидалась ошибка короткого пароля, получено %v", err)
+	}
+	user, err := pkg.RegisterUser("Alice", "secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pkg.RegisterUser("alice", "secret-password"); err != pkg.ErrUserExists {
+		t.Errorf("Ожидалась ошибка повторной регистрации, получено %v", err)
+	}
+	if _, err := pkg.AuthenticateUser("alice", "wrong-password"); err != pkg.ErrInvalidCredentials {
+		t.Errorf("Ожидалась ошибка входа, получено %v", err)
+	}
+
+	// Вход через форму устанавливает защищенную cookie сессии
+	form := url.Values{"username": {"alice"}, "password": {"secret-password"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	pkg.LoginHandler(rec, req)
+
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/my" {
+		t.Fatalf("Ожидалось перенаправление на /my, получено %v %v", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Неверная cookie сессии: %v", cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	current, ok := pkg.CurrentUser(req)
+	if !ok || current.ID != user.ID {
+		t.Fatalf("Сессия не найдена")
+	}
+
+	// Сессии и подписки сохраняются в хранилище
+	if err := pkg.SetFollow(user.ID, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.OpenStorage(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.LoadUsers(); err != nil {
+		t.Fatal(err)
+	}
+	current, ok = pkg.CurrentUser(req)
+	if !ok || !current.IsFollowing(2) {
+		t.Errorf("Подписка на группу не сохранилась: %v", current.Follows)
+	}
+
+	// Главная страница показывает вошедшему пользователю концерты его групп
+	saved := pkg.ResponseData
+	defer func() { pkg.ResponseData = saved }()
+	pkg.FillData([]pkg.Band{
+		{ID: 1, Name: "Queen", Relations: map[string][]string{"london-uk": {"01-01-2099"}}},
+		{ID: 2, Name: "SOJA", Relations: map[string][]string{"paris-france": {"01-02-2099"}}},
+	})
+	home := httptest.NewRecorder()
+	pkg.HomeHandler(home, req)
+	if body := home.Body.String(); home.Code != http.StatusOK || !strings.Contains(body, "Paris, France") || strings.Contains(body, "London, UK") {
+		t.Errorf("На главной странице нет концертов отслеживаемых групп: %d\n%s", home.Code, body)
+	}
+	home = httptest.NewRecorder()
+	pkg.HomeHandler(home, httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Contains(home.Body.String(), "Paris, France") {
+		t.Errorf("Анонимному посетителю не должны показываться концерты подписок")
+	}
+
+	if err := pkg.DeleteSession(cookies[0].Value); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pkg.CurrentUser(req); ok {
+		t.Errorf("Сессия должна быть удалена")
+	}
+
+	// Без хранилища регистрация и вход отключены, а не сохраняются только в памяти
+	if err := pkg.CloseStorage(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.LoadUsers(); !errors.Is(err, pkg.ErrUserStorageUnavailable) {
+		t.Errorf("Ожидалась ошибка недоступного хранилища, получено %v", err)
+	}
+	if _, err := pkg.RegisterUser("bob", "secret-password"); !errors.Is(err, pkg.ErrUserStorageUnavailable) {
+		t.Errorf("Ожидалась ошибка недоступного хранилища при регистрации, получено %v", err)
+	}
+	for _, target := range []string{"/register", "/login"} {
+		form := url.Values{"username": {"alice"}, "password": {"secret-password"}}
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		if target == "/register" {
+			pkg.RegisterHandler(rec, req)
+		} else {
+			pkg.LoginHandler(rec, req)
+		}
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "temporarily unavailable") {
+			t.Errorf("%s: ожидался статус 503, получен %d", target, rec.Code)
+		}
+	}
+}
+
+// Тест 16 для проверки списка предстоящих концертов
+func TestUpcomingConcerts(t *testing.T) {
+	bands := []pkg.Band{
+		{ID: 1, Name: "Queen", Relations: map[string][]string{"london-uk": {"01-01-2020", "05-03-2031"}}},
+		{ID: 2, Name: "SOJA", Relations: map[string][]string{"paris-france": {"*01-02-2031"}}},
+		{ID: 3, Name: "Pink Floyd", Relations: map[string][]string{"berlin-germany": {"01-01-2031"}}},
+	}
+	now := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	list := pkg.UpcomingConcerts(bands, []int{1, 2}, now)
+	if len(list) != 2 {
+		t.Fatalf("Ожидалось 2 концерта, получено %v", list)
+	}
+	if list[0].BandName != "SOJA" || list[1].BandName != "Queen" || list[1].Location != "London, UK" {
+		t.Errorf("Неверный порядок концертов: %v", list)
+	}
+}
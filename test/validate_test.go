@@ -0,0 +1,82 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 11 для проверки данных при загрузке
+func TestValidateSnapshot(t *testing.T) {
+	s := pkg.Snapshot{
+		Bands: []pkg.Band{
+			{ID: 1, Name: "Queen", Image: "https://example.com/queen.jpeg", Members: []string{"Freddie Mercury"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+			{ID: -2, Name: "Negative", Image: "https://example.com/n.jpeg", Members: []string{"A"}, CreationDate: 1980, FirstAlbum: "01-01-1981"},
+			{ID: 3, Name: "", Image: "not a url", CreationDate: 1990, FirstAlbum: "01-01-1991"},
+			{ID: 4, Name: "Future", Image: "https://example.com/f.jpeg", Members: []string{"B"}, CreationDate: 3000, FirstAlbum: "01-01-3001"},
+		},
+		Relations: relations(map[int]map[string][]string{
+			1:  {"london-uk": {"01-01-1960", "bad"}},
+			-2: {"paris-france": {"01-01-1990"}},
+		}),
+	}
+
+	valid, report := pkg.ValidateSnapshot(s)
+
+	if len(valid.Bands) != 1 || valid.Bands[0].ID != 1 {
+		t.Fatalf("Ожидалась одна корректная группа, получено %+v", valid.Bands)
+	}
+	if len(valid.Relations.Index) != 1 {
+		t.Errorf("Связи исключенных групп должны быть удалены: %+v", valid.Relations.Index)
+	}
+	if len(report.Quarantined) != 3 {
+		t.Errorf("Ожидалось 3 исключенные группы, получено %v", len(report.Quarantined))
+	}
+	if report.Errors != 3 {
+		t.Errorf("Ожидалось 3 ошибки, получено %v: %+v", report.Errors, report.Issues)
+	}
+
+	warnings := map[string]bool{}
+	for _, issue := range report.Issues {
+		if issue.Severity == pkg.SeverityWarning && issue.RecordID == 1 {
+			warnings[issue.Field] = true
+		}
+	}
+	// Концерт до создания группы, некорректная дата и отсутствие локаций
+	if !warnings["datesLocations"] || !warnings["id"] {
+		t.Errorf("Ожидались предупреждения для группы 1: %+v", report.Issues)
+	}
+
+	// Дубль номера исключает только вторую запись, а записи без номера проверяются по отдельности
+	band := func(id int, name string) pkg.Band {
+		return pkg.Band{ID: id, Name: name, Image: "https://example.com/b.jpeg", Members: []string{"A"}, CreationDate: 1970, FirstAlbum: "01-01-1971"}
+	}
+	s = pkg.Snapshot{
+		Bands: []pkg.Band{band(1, "Queen"), band(1, "Queen copy"), band(0, "Zero"), band(0, "")},
+		Relations: relations(map[int]map[string][]string{
+			1: {"london-uk": {"01-01-1980"}},
+			0: {"paris-france": {"01-01-1980"}},
+		}),
+	}
+	valid, report = pkg.ValidateSnapshot(s)
+
+	if len(valid.Bands) != 1 || valid.Bands[0].Name != "Queen" {
+		t.Fatalf("Должна остаться первая запись с номером 1, получено %+v", valid.Bands)
+	}
+	if len(valid.Relations.Index) != 1 || valid.Relations.Index[0].ID != 1 {
+		t.Errorf("Связи первой записи с номером 1 должны остаться: %+v", valid.Relations.Index)
+	}
+	quarantined := map[string][]string{}
+	for _, q := range report.Quarantined {
+		quarantined[q.Band.Name] = q.Issues
+	}
+	want := map[string]int{"Queen copy": 1, "Zero": 1, "": 2}
+	if len(quarantined) != len(want) {
+		t.Errorf("Неверный список исключенных групп: %+v", report.Quarantined)
+	}
+	for name, n := range want {
+		if len(quarantined[name]) != n {
+			t.Errorf("Запись %q: ожидалось %d ошибок, получено %v", name, n, quarantined[name])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package pkg_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lzhuk/groupie-tracker/pkg"
+)
+
+// Тест 5 для проверки доставки уведомлений подписчикам
+func TestDeliverWebhooks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "webhooks.json")
+	t.Setenv("WEBHOOKS_FILE", file)
+	pkg.WebhookRetryDelay = 10 * time.Millisecond
+
+	var (
+		mu        sync.Mutex
+		calls     int
+		body      []byte
+		signature string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// Первая попытка завершается ошибкой, чтобы проверить повторную отправку
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Groupie-Signature")
+	}))
+	defer receiver.Close()
+
+	hook, err := pkg.RegisterWebhook(receiver.URL, []int{1}, []string{"london"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkg.DeleteWebhook(hook.ID)
+
+	if _, err := pkg.RegisterWebhook("ftp://example.com", nil, nil); err == nil {
+		t.Errorf("Ожидалась ошибка для некорректного адреса")
+	}
+
+	diff := pkg.SnapshotDiff{Changed: []pkg.BandChange{
+		{
+			BandRef: pkg.BandRef{ID: 1, Name: "Queen"},
+			AddedConcerts: []pkg.Concert{
+				{Location: "london-uk", Date: "05-01-2020"},
+				{Location: "paris-france", Date: "06-01-2020"},
+			},
+		},
+		{
+			BandRef:       pkg.BandRef{ID: 2, Name: "SOJA"},
+			AddedConcerts: []pkg.Concert{{Location: "london-uk", Date: "07-01-2020"}},
+		},
+	}}
+
+	pkg.DeliverWebhooks(diff, pkg.Snapshot{})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls != 2 {
+		t.Errorf("Ожидалось 2 попытки доставки, получено %v", calls)
+	}
+	if signature != pkg.SignWebhookPayload(hook.Secret, body) {
+		t.Errorf("Неверная подпись уведомления: %v", signature)
+	}
+	if !strings.Contains(string(body), "london-uk") || strings.Contains(string(body), "paris-france") ||
+		strings.Contains(string(body), "SOJA") {
+		t.Errorf("Уведомление не соответствует фильтрам подписки: %s", body)
+	}
+
+	deliveries := pkg.WebhookDeliveries()
+	if len(deliveries) == 0 || !deliveries[0].Success || deliveries[0].Attempts != 2 {
+		t.Errorf("Неверная запись в журнале доставки: %+v", deliveries)
+	}
+
+	// Файл с секретами доступен только владельцу
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Файл подписок должен иметь права 0600: %v", err)
+	}
+
+	// Если сохранить подписки не удалось, список в памяти не меняется
+	t.Setenv("WEBHOOKS_FILE", filepath.Join(t.TempDir(), "missing", "webhooks.json"))
+	if _, err := pkg.RegisterWebhook(receiver.URL, nil, nil); err == nil {
+		t.Errorf("Ожидалась ошибка сохранения подписки")
+	}
+	if err := pkg.DeleteWebhook(hook.ID); err == nil {
+		t.Errorf("Ожидалась ошибка сохранения после удаления подписки")
+	}
+	if list := pkg.ListWebhooks(); len(list) != 1 || list[0].ID != hook.ID {
+		t.Errorf("Список подписок изменился после ошибки сохранения: %+v", list)
+	}
+	t.Setenv("WEBHOOKS_FILE", file)
+}
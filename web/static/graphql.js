@@ -0,0 +1,31 @@
+// Отправка запросов со страницы GraphQL playground
+(function () {
+  var form = document.getElementById("graphql-form");
+  var query = document.getElementById("graphql-query");
+  var variables = document.getElementById("graphql-variables");
+  var result = document.getElementById("graphql-result");
+
+  form.addEventListener("submit", function (e) {
+    e.preventDefault();
+
+    var body = { query: query.value };
+    if (variables.value.trim() !== "") {
+      try {
+        body.variables = JSON.parse(variables.value);
+      } catch (err) {
+        result.textContent = "Variables: " + err.message;
+        return;
+      }
+    }
+
+    result.textContent = "...";
+    fetch("/graphql", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(body)
+    })
+      .then(function (resp) { return resp.json(); })
+      .then(function (data) { result.textContent = JSON.stringify(data, null, 2); })
+      .catch(function (err) { result.textContent = err.message; });
+  });
+})();
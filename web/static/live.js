@@ -0,0 +1,32 @@
+// Подписка на обновления данных: показывает плашку, когда данные на странице устарели
+(function () {
+  if (!window.EventSource) {
+    return;
+  }
+
+  var banner = document.getElementById("live-update");
+  var bandId = parseInt(document.body.dataset.bandId || "0", 10);
+  var version = null;
+  var source = new EventSource("/events");
+
+  function showBanner() {
+    banner.hidden = false;
+  }
+
+  // После переподключения версия могла измениться, пока соединения не было
+  source.addEventListener("hello", function (e) {
+    var v = JSON.parse(e.data).version;
+    if (version !== null && v !== version) {
+      showBanner();
+    }
+    version = v;
+  });
+
+  source.addEventListener("update", function (e) {
+    var update = JSON.parse(e.data);
+    version = update.version;
+    if (!bandId || update.changedBands.indexOf(bandId) >= 0 || update.removedBands.indexOf(bandId) >= 0) {
+      showBanner();
+    }
+  });
+})();